@@ -0,0 +1,80 @@
+// kafka-offsets inspects and resets the committed offsets of the worker
+// consumer groups.
+//
+//	kafka-offsets describe -group sms-normal-worker -topic sms-normal
+//	kafka-offsets reset -group sms-normal-worker -topic sms-normal -to latest -execute
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"arvan-sms-gateway/internal/config"
+	"arvan-sms-gateway/internal/queue"
+)
+
+func usage() {
+	fmt.Fprintln(os.Stderr, `usage: kafka-offsets <describe|reset> [flags]
+
+  describe  show committed offset and lag per partition
+  reset     move the group's committed offsets (all workers of the group must be stopped)
+
+Run "kafka-offsets <command> -h" for the flags of a command.`)
+	os.Exit(2)
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+	cfg := config.MustLoad(nil)
+
+	fs := flag.NewFlagSet(os.Args[1], flag.ExitOnError)
+	brokers := fs.String("brokers", cfg.KafkaBrokers, "comma separated Kafka brokers")
+	group := fs.String("group", cfg.KafkaGroupNormal, "consumer group id")
+	topic := fs.String("topic", cfg.KafkaTopicNormal, "topic")
+
+	var offsets []queue.PartitionOffset
+	var err error
+	switch os.Args[1] {
+	case "describe":
+		fs.Parse(os.Args[2:])
+		offsets, err = queue.DescribeGroupOffsets(strings.Split(*brokers, ","), *group, *topic)
+	case "reset":
+		to := fs.String("to", "", "earliest, latest, an absolute offset, or an RFC3339 timestamp")
+		execute := fs.Bool("execute", false, "apply the reset; without it only the plan is printed")
+		fs.Parse(os.Args[2:])
+		if *to == "" {
+			fmt.Fprintln(os.Stderr, "reset: -to is required")
+			os.Exit(2)
+		}
+		offsets, err = queue.ResetGroupOffsets(strings.Split(*brokers, ","), *group, *topic, *to, *execute)
+		if err == nil && !*execute {
+			defer fmt.Println("dry run: re-run with -execute to commit the target offsets")
+		}
+	default:
+		usage()
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(w, "GROUP\tTOPIC\tPARTITION\tOLDEST\tNEWEST\tCOMMITTED\tLAG")
+	if os.Args[1] == "reset" {
+		fmt.Fprintf(w, "\tTARGET")
+	}
+	fmt.Fprintln(w)
+	for _, po := range offsets {
+		fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%d\t%d\t%d", *group, *topic, po.Partition, po.Oldest, po.Newest, po.Committed, po.Lag())
+		if os.Args[1] == "reset" {
+			fmt.Fprintf(w, "\t%d", po.Target)
+		}
+		fmt.Fprintln(w)
+	}
+	w.Flush()
+}
@@ -0,0 +1,95 @@
+// migrate applies the versioned schema migrations embedded from migrations/.
+//
+//	migrate status
+//	migrate up [-n N]
+//	migrate down [-n N]
+//	migrate redo
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"arvan-sms-gateway/internal/config"
+	"arvan-sms-gateway/internal/migrate"
+	"arvan-sms-gateway/migrations"
+
+	_ "github.com/lib/pq"
+)
+
+func usage() {
+	fmt.Fprintln(os.Stderr, `usage: migrate <status|up|down|redo> [flags]
+
+  status  list migrations and when they were applied
+  up      apply pending migrations (-n limits how many)
+  down    revert the last applied migration (-n reverts more)
+  redo    revert and re-apply the last applied migration`)
+	os.Exit(2)
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+	cfg := config.MustLoad(nil)
+
+	fs := flag.NewFlagSet(os.Args[1], flag.ExitOnError)
+	dbURL := fs.String("database", cfg.DBUrl, "Postgres connection URL")
+	n := fs.Int("n", 0, "number of migrations to apply or revert")
+	fs.Parse(os.Args[2:])
+
+	db, err := sql.Open("postgres", *dbURL)
+	if err != nil {
+		fail(err)
+	}
+	defer db.Close()
+
+	runner, err := migrate.NewRunner(db, migrations.FS)
+	if err != nil {
+		fail(err)
+	}
+
+	ctx := context.Background()
+	applied := func(m migrate.Migration) { fmt.Printf("applied  %03d_%s\n", m.Version, m.Name) }
+	reverted := func(m migrate.Migration) { fmt.Printf("reverted %03d_%s\n", m.Version, m.Name) }
+
+	switch os.Args[1] {
+	case "status":
+		status, err := runner.Status(ctx)
+		if err != nil {
+			fail(err)
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+		for _, s := range status {
+			at := "pending"
+			if s.AppliedAt != nil {
+				at = s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Fprintf(w, "%03d\t%s\t%s\n", s.Version, s.Name, at)
+		}
+		w.Flush()
+	case "up":
+		err = runner.Up(ctx, *n, applied)
+	case "down":
+		err = runner.Down(ctx, *n, reverted)
+	case "redo":
+		if err = runner.Down(ctx, 1, reverted); err == nil {
+			err = runner.Up(ctx, 1, applied)
+		}
+	default:
+		usage()
+	}
+	if err != nil {
+		fail(err)
+	}
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, "error:", err)
+	os.Exit(1)
+}
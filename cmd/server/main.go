@@ -5,43 +5,100 @@
 package main
 
 import (
+	"arvan-sms-gateway/internal/alerts"
 	"arvan-sms-gateway/internal/api"
+	"arvan-sms-gateway/internal/breaker"
 	"arvan-sms-gateway/internal/cache"
 	"arvan-sms-gateway/internal/config"
 	"arvan-sms-gateway/internal/db"
+	"arvan-sms-gateway/internal/health"
+	"arvan-sms-gateway/internal/jobs"
 	"arvan-sms-gateway/internal/logger"
 	"arvan-sms-gateway/internal/metrics"
+	"arvan-sms-gateway/internal/migrate"
+	"arvan-sms-gateway/internal/otp"
 	"arvan-sms-gateway/internal/queue"
+	"arvan-sms-gateway/internal/quiethours"
+	"arvan-sms-gateway/internal/quota"
 	"arvan-sms-gateway/internal/service"
+	"arvan-sms-gateway/internal/tracing"
+	"arvan-sms-gateway/internal/webhook"
+	"arvan-sms-gateway/migrations"
+	"context"
+	"errors"
 	"github.com/gin-gonic/gin"
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 	"go.uber.org/zap"
+	"net/http"
+	"os"
 	"strings"
 
 	_ "arvan-sms-gateway/docs"
 )
 
 func main() {
-	cfg := config.LoadEnv()
+	cfg := config.MustLoad(os.Args[1:])
 
 	logger.InitLogger()
 	defer logger.Sync()
+	config.Watch()
+	breaker.Init(cfg)
 	metrics.InitMetrics()
+	metrics.Serve(cfg.ServerMetricsPort)
+	shutdownTracing, err := tracing.Init(cfg, cfg.ServiceName)
+	if err != nil {
+		logger.Error("Tracing init failed", zap.Error(err))
+		panic(err)
+	}
+	defer shutdownTracing(context.Background())
 
 	db.InitDB(cfg.DBUrl)
+	if cfg.RequireSchema {
+		if err := migrate.CheckCurrent(context.Background(), db.DB, migrations.FS); err != nil {
+			logger.Error("Refusing to start", zap.Error(err))
+			panic(err)
+		}
+	}
+	health.Register("postgres", db.DB.PingContext)
 	service.InitService(cfg)
+	health.Register("reservations", service.Reservations().Ping)
+	webhook.Init(cfg.WebhookAllowPrivate)
+	alerts.Init(db.DB, service.AlertSMSSender())
 	cache.InitRedis(cfg.RedisAddr)
+	health.Register("redis", cache.Ping)
+	otp.Init(cfg.RedisAddr)
+	if err := quota.Init(cfg.RedisAddr, cfg.QuotaTimezone); err != nil {
+		logger.Error("Quota init failed", zap.Error(err))
+		panic(err)
+	}
+	if err := quiethours.Init(cfg.QuietHours, cfg.QuietHoursTimezone, cfg.QuietHoursOverrides); err != nil {
+		logger.Error("Quiet hours init failed", zap.Error(err))
+		panic(err)
+	}
+
+	// Anyone who can post inbound messages can opt numbers out and trigger
+	// charged auto-replies, so the endpoint is never open by accident.
+	if cfg.InboundToken == "" && !cfg.InboundInsecure {
+		err := errors.New("inbound_token is required unless inbound_insecure is set")
+		logger.Error("Refusing to start", zap.Error(err))
+		panic(err)
+	}
 
 	r := gin.Default()
+	r.Use(otelgin.Middleware(cfg.ServiceName, otelgin.WithFilter(func(req *http.Request) bool {
+		return req.URL.Path != "/healthz" && req.URL.Path != "/readyz"
+	})))
 
 	// Only enable Swagger + CORS in Developer Mode
-	if cfg.DeveloperMode == "true" {
+	if cfg.DeveloperMode {
 		// CORS
 		r.Use(func(c *gin.Context) {
 			c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
 			c.Writer.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-			c.Writer.Header().Set("Access-Control-Allow-Headers", "Origin, Content-Type, Authorization")
+			c.Writer.Header().Set("Access-Control-Allow-Headers", "Origin, Content-Type, Authorization, X-Request-ID")
+			c.Writer.Header().Set("Access-Control-Expose-Headers", "X-Request-ID")
 			if c.Request.Method == "OPTIONS" {
 				c.AbortWithStatus(200)
 				return
@@ -54,7 +111,12 @@ func main() {
 
 	api.RegisterRoutes(r, cfg)
 
-	//jobs.StartRefundJob(db.DB, 60*time.Second, 10000) ##TODO is not scalable for just MVP
+	if cfg.RefundInterval > 0 {
+		jobs.StartRefundJob(service.Reservations(), cfg.RefundInterval, cfg.RefundBatchSize)
+	}
+	if cfg.ReconcileInterval > 0 {
+		jobs.StartWalletReconciler(service.Reservations(), cfg.ReconcileInterval, cfg.ReconcileDryRun)
+	}
 
 	brokers := strings.Split(cfg.KafkaBrokers, ",")
 	if err := queue.InitKafka(brokers); err != nil {
@@ -62,6 +124,16 @@ func main() {
 		panic(err)
 	}
 	defer queue.Close()
+	health.Register("kafka_producer", queue.Ping)
+	if cfg.ParkedRetryInterval > 0 {
+		jobs.StartParkedRetry(cfg.ParkedRetryInterval)
+	}
+	if cfg.InboundWebhookInterval > 0 {
+		jobs.StartInboundWebhooks(cfg.InboundWebhookInterval, cfg.InboundWebhookMaxAttempts)
+	}
+	if cfg.CampaignDispatcher {
+		jobs.StartCampaignDispatcher(service.DispatchCampaignChunk)
+	}
 
 	logger.Info("Starting service",
 		zap.String("service", cfg.ServiceName),
@@ -0,0 +1,49 @@
+// wallet-reconcile runs one Redis-vs-Postgres wallet reconciliation and prints
+// the drift it found. It only reports unless -dry-run=false is given.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"arvan-sms-gateway/internal/config"
+	"arvan-sms-gateway/internal/db"
+	"arvan-sms-gateway/internal/jobs"
+	"arvan-sms-gateway/internal/logger"
+	"arvan-sms-gateway/internal/reservation"
+)
+
+func main() {
+	dryRun := flag.Bool("dry-run", true, "only report drift, do not correct Redis")
+	flag.Parse()
+
+	cfg := config.MustLoad(nil)
+	logger.InitLogger()
+	defer logger.Sync()
+	db.InitDB(cfg.DBUrl)
+
+	svc := reservation.NewService(db.DB, cfg.RedisAddr, cfg.ReservationTTL)
+	report, err := jobs.RunWalletReconcile(context.Background(), svc, *dryRun)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+	if report.Skipped {
+		fmt.Println("another reconciler is running, try again later")
+		return
+	}
+
+	fmt.Printf("checked=%d drifted=%d corrected=%d negative=%d orphaned=%d dry_run=%v\n",
+		report.Checked, len(report.Drifted), report.Corrected, len(report.Negative), len(report.Orphaned), *dryRun)
+	for _, d := range report.Drifted {
+		fmt.Printf("drift user=%s redis=%d postgres=%d corrected=%v\n", d.UserID, d.Redis, d.Postgres, d.Corrected)
+	}
+	for _, id := range report.Negative {
+		fmt.Printf("negative user=%s\n", id)
+	}
+	for _, id := range report.Orphaned {
+		fmt.Printf("orphaned user=%s\n", id)
+	}
+}
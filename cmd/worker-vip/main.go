@@ -1,21 +1,63 @@
 package main
 
 import (
+	"arvan-sms-gateway/internal/alerts"
+	"arvan-sms-gateway/internal/breaker"
 	"arvan-sms-gateway/internal/config"
 	"arvan-sms-gateway/internal/db"
+	"arvan-sms-gateway/internal/health"
 	"arvan-sms-gateway/internal/logger"
+	"arvan-sms-gateway/internal/metrics"
+	"arvan-sms-gateway/internal/queue"
+	"arvan-sms-gateway/internal/quiethours"
+	"arvan-sms-gateway/internal/service"
+	"arvan-sms-gateway/internal/throttle"
+	"arvan-sms-gateway/internal/tracing"
+	"arvan-sms-gateway/internal/webhook"
 	"arvan-sms-gateway/internal/worker"
+	"context"
+	"go.uber.org/zap"
+	"os"
 	"strings"
-	"time"
 )
 
 func main() {
-	cfg := config.LoadEnv()
+	cfg := config.MustLoad(os.Args[1:])
 	logger.InitLogger()
 	defer logger.Sync()
+	config.Watch()
+	breaker.Init(cfg)
+	metrics.InitMetrics()
+	metrics.Serve(cfg.VIPMetricsPort)
+	shutdownTracing, err := tracing.Init(cfg, cfg.ServiceName+"-worker-vip")
+	if err != nil {
+		logger.Error("Tracing init failed", zap.Error(err))
+		panic(err)
+	}
+	defer shutdownTracing(context.Background())
 	db.InitDB(cfg.DBUrl)
+	health.Register("postgres", db.DB.PingContext)
+	health.Serve(cfg.VIPHealthPort)
+	if err := quiethours.Init(cfg.QuietHours, cfg.QuietHoursTimezone, cfg.QuietHoursOverrides); err != nil {
+		logger.Error("Quiet hours init failed", zap.Error(err))
+		panic(err)
+	}
+	throttle.Init(cfg.RedisAddr)
 
-	brokers := strings.Split(cfg.KafkaBrokers, ",")
-	groupID := "vip-worker-group-" + time.Now().Format("150405")
-	worker.StartWorker(brokers, cfg.KafkaTopicVIP, groupID, true)
+	// Low-balance SMS go through the regular send path, which needs the producer.
+	if cfg.AlertSenderUserID != "" {
+		if err := queue.InitKafka(strings.Split(cfg.KafkaBrokers, ",")); err != nil {
+			logger.Error("Kafka producer init failed", zap.Error(err))
+			panic(err)
+		}
+		defer queue.Close()
+		health.Register("kafka_producer", queue.Ping)
+		service.InitService(cfg)
+	}
+	webhook.Init(cfg.WebhookAllowPrivate)
+	alerts.Init(db.DB, service.AlertSMSSender())
+
+	// All replicas share cfg.KafkaGroupVIP so they split the partitions and resume
+	// from the group's committed offsets after a restart.
+	worker.StartWorker(cfg, cfg.KafkaTopicVIP, cfg.KafkaGroupVIP, true)
 }
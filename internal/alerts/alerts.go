@@ -0,0 +1,276 @@
+package alerts
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"arvan-sms-gateway/internal/logger"
+	"arvan-sms-gateway/internal/metrics"
+	"arvan-sms-gateway/internal/webhook"
+	"github.com/lib/pq"
+	"go.uber.org/zap"
+)
+
+// SMSSender sends a notification SMS to phone. It is provided by the binary
+// so that alerts go through the regular send path.
+type SMSSender func(phone, text string) error
+
+// Event is emitted once when a balance drops to or below a threshold.
+type Event struct {
+	UserID    string    `json:"user_id"`
+	Threshold int64     `json:"threshold"`
+	Balance   int64     `json:"balance"`
+	At        time.Time `json:"at"`
+}
+
+type Threshold struct {
+	Threshold   int64      `json:"threshold"`
+	Triggered   bool       `json:"triggered"`
+	TriggeredAt *time.Time `json:"triggered_at,omitempty"`
+}
+
+type Settings struct {
+	Thresholds  []Threshold `json:"thresholds"`
+	Hysteresis  int64       `json:"hysteresis"`
+	NotifyPhone string      `json:"notify_phone,omitempty"`
+	WebhookURL  string      `json:"webhook_url,omitempty"`
+}
+
+// State is "low" while any threshold is triggered, "ok" otherwise.
+func (s *Settings) State() string {
+	for _, t := range s.Thresholds {
+		if t.Triggered {
+			return "low"
+		}
+	}
+	return "ok"
+}
+
+// configuredTTL is how long a replica remembers whether a user has any
+// thresholds, which saves two queries per debit for users without alerts.
+const configuredTTL = 30 * time.Second
+
+var (
+	db         *sql.DB
+	sendSMS    SMSSender
+	httpClient = webhook.NewClient(5 * time.Second)
+
+	configured sync.Map // user ID -> configuredEntry
+)
+
+type configuredEntry struct {
+	has     bool
+	expires time.Time
+}
+
+func Init(database *sql.DB, sms SMSSender) {
+	db = database
+	sendSMS = sms
+}
+
+func hasThresholds(userID string) (bool, error) {
+	if v, ok := configured.Load(userID); ok {
+		e := v.(configuredEntry)
+		if time.Now().Before(e.expires) {
+			return e.has, nil
+		}
+	}
+	var has bool
+	err := db.QueryRow(`SELECT EXISTS (SELECT 1 FROM balance_alert_thresholds WHERE user_id=$1)`, userID).Scan(&has)
+	if err != nil {
+		return false, err
+	}
+	configured.Store(userID, configuredEntry{has: has, expires: time.Now().Add(configuredTTL)})
+	return has, nil
+}
+
+// Evaluate is called with the available balance (balance + credit limit)
+// left after every debit or credit. A threshold fires once when the balance
+// reaches it and re-arms only after the balance climbs above threshold +
+// hysteresis, so a balance hovering around a threshold does not alert on
+// every message. The conditional updates make
+// each crossing fire on exactly one replica.
+func Evaluate(userID string, balance int64) {
+	if db == nil {
+		return
+	}
+	has, err := hasThresholds(userID)
+	if err != nil || !has {
+		return
+	}
+
+	_, err = db.Exec(`
+        UPDATE balance_alert_thresholds SET triggered=false, triggered_at=NULL
+        WHERE user_id=$1 AND triggered
+          AND $2 > threshold + COALESCE((SELECT hysteresis FROM balance_alert_settings WHERE user_id=$1), 0)`,
+		userID, balance)
+	if err != nil {
+		logger.Error("Failed to re-arm balance alerts", zap.String("user_id", userID), zap.Error(err))
+		return
+	}
+
+	rows, err := db.Query(`
+        UPDATE balance_alert_thresholds SET triggered=true, triggered_at=NOW()
+        WHERE user_id=$1 AND NOT triggered AND $2 <= threshold
+        RETURNING threshold`, userID, balance)
+	if err != nil {
+		logger.Error("Failed to evaluate balance alerts", zap.String("user_id", userID), zap.Error(err))
+		return
+	}
+	defer rows.Close()
+
+	var crossed []int64
+	for rows.Next() {
+		var t int64
+		if err := rows.Scan(&t); err != nil {
+			logger.Error("Failed to evaluate balance alerts", zap.String("user_id", userID), zap.Error(err))
+			return
+		}
+		crossed = append(crossed, t)
+	}
+	if len(crossed) == 0 {
+		return
+	}
+
+	// Only the lowest crossed threshold is announced when a single debit
+	// crosses several of them.
+	lowest := crossed[0]
+	for _, t := range crossed[1:] {
+		if t < lowest {
+			lowest = t
+		}
+	}
+	go emit(Event{UserID: userID, Threshold: lowest, Balance: balance, At: time.Now()})
+}
+
+func emit(ev Event) {
+	logger.Info("Low balance threshold crossed",
+		zap.String("user_id", ev.UserID),
+		zap.Int64("threshold", ev.Threshold),
+		zap.Int64("balance", ev.Balance))
+
+	settings, err := GetSettings(ev.UserID)
+	if err != nil {
+		logger.Error("Failed to load balance alert settings", zap.String("user_id", ev.UserID), zap.Error(err))
+		return
+	}
+
+	if settings.WebhookURL != "" {
+		err := postWebhook(settings.WebhookURL, ev)
+		record("webhook", ev, err)
+	}
+	if settings.NotifyPhone != "" && sendSMS != nil {
+		text := fmt.Sprintf("Your SMS wallet balance is %d, at or below your alert threshold of %d.", ev.Balance, ev.Threshold)
+		err := sendSMS(settings.NotifyPhone, text)
+		record("sms", ev, err)
+	}
+}
+
+func postWebhook(url string, ev Event) error {
+	body, _ := json.Marshal(map[string]any{"type": "balance.low", "data": ev})
+
+	var err error
+	for attempt := 0; attempt < 3; attempt++ {
+		if attempt > 0 {
+			time.Sleep(time.Duration(attempt) * time.Second)
+		}
+		var resp *http.Response
+		resp, err = httpClient.Post(url, "application/json", bytes.NewReader(body))
+		if err != nil {
+			continue
+		}
+		resp.Body.Close()
+		if resp.StatusCode < 300 {
+			return nil
+		}
+		err = fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return err
+}
+
+func record(channel string, ev Event, err error) {
+	outcome := "sent"
+	if err != nil {
+		outcome = "failed"
+		logger.Error("Low balance notification failed",
+			zap.String("channel", channel),
+			zap.String("user_id", ev.UserID),
+			zap.Error(err))
+	}
+	metrics.BalanceAlerts.WithLabelValues(channel, outcome).Inc()
+}
+
+// GetSettings returns the user's thresholds and notification channels. A user
+// without settings gets an empty, "ok" configuration.
+func GetSettings(userID string) (*Settings, error) {
+	s := &Settings{Thresholds: []Threshold{}}
+	var phone, webhook sql.NullString
+	err := db.QueryRow(`SELECT hysteresis, notify_phone, webhook_url FROM balance_alert_settings WHERE user_id=$1`, userID).
+		Scan(&s.Hysteresis, &phone, &webhook)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	s.NotifyPhone, s.WebhookURL = phone.String, webhook.String
+
+	rows, err := db.Query(`
+        SELECT threshold, triggered, triggered_at FROM balance_alert_thresholds
+        WHERE user_id=$1 ORDER BY threshold DESC`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var t Threshold
+		var at sql.NullTime
+		if err := rows.Scan(&t.Threshold, &t.Triggered, &at); err != nil {
+			return nil, err
+		}
+		if at.Valid {
+			t.TriggeredAt = &at.Time
+		}
+		s.Thresholds = append(s.Thresholds, t)
+	}
+	return s, rows.Err()
+}
+
+// SaveSettings replaces the user's alert configuration. Thresholds that are
+// kept retain their triggered state; removed ones are dropped.
+func SaveSettings(userID string, thresholds []int64, hysteresis int64, phone, webhook string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+        INSERT INTO balance_alert_settings (user_id, hysteresis, notify_phone, webhook_url, updated_at)
+        VALUES ($1, $2, NULLIF($3, ''), NULLIF($4, ''), NOW())
+        ON CONFLICT (user_id) DO UPDATE
+        SET hysteresis = EXCLUDED.hysteresis, notify_phone = EXCLUDED.notify_phone,
+            webhook_url = EXCLUDED.webhook_url, updated_at = NOW()`,
+		userID, hysteresis, phone, webhook)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM balance_alert_thresholds WHERE user_id=$1 AND NOT (threshold = ANY($2))`,
+		userID, pq.Array(thresholds)); err != nil {
+		return err
+	}
+	for _, t := range thresholds {
+		if _, err := tx.Exec(`
+            INSERT INTO balance_alert_thresholds (user_id, threshold) VALUES ($1, $2)
+            ON CONFLICT DO NOTHING`, userID, t); err != nil {
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	configured.Delete(userID)
+	return nil
+}
@@ -0,0 +1,226 @@
+package api
+
+import (
+	"arvan-sms-gateway/internal/config"
+	"arvan-sms-gateway/internal/db"
+	"arvan-sms-gateway/internal/models"
+	"arvan-sms-gateway/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// maxAutoReplyCooldown bounds a rule's cooldown at 30 days.
+const maxAutoReplyCooldown = 30 * 24 * 3600
+
+var tagPattern = regexp.MustCompile(`^[A-Za-z0-9_.:-]{1,64}$`)
+
+func RegisterAutoReplyRoutes(r *gin.Engine, cfg *config.Config) {
+	r.POST("/auto-replies", createAutoReplyRule)
+	r.GET("/auto-replies", listAutoReplyRules)
+	r.DELETE("/auto-replies/:id", deleteAutoReplyRule)
+	r.GET("/opt-outs", listOptOuts)
+	r.GET("/contact-tags", listContactTags)
+}
+
+// @Summary Create auto-reply rule
+// @Description Answer inbound messages to one of the user's routed numbers. match_type exact matches the whole text, prefix its first word, contains any part of it, and any every text (keyword empty); the most specific matching rule wins. The reply may use {from}, {to} and {keyword} and is sent and charged like any transactional message; cooldown_seconds suppresses further replies of the rule to the same sender. action opt_out stops marketing messages to the sender, opt_in undoes that, tag adds tag to the sender.
+// @Tags Inbound
+// @Accept  json
+// @Produce  json
+// @Param   request body models.AutoReplyRuleRequest true "Rule"
+// @Success 201 {object} models.AutoReplyRule "Rule created"
+// @Failure 400 {object} map[string]interface{} "Invalid rule or number not routed to the user"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /auto-replies [post]
+func createAutoReplyRule(c *gin.Context) {
+	var req models.AutoReplyRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid JSON payload"})
+		return
+	}
+	if _, err := uuid.Parse(req.UserID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user_id format (must be UUID)"})
+		return
+	}
+
+	keyword := strings.ToUpper(strings.Join(strings.Fields(req.Keyword), " "))
+	switch req.MatchType {
+	case models.MatchAny:
+		if keyword != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "match_type any takes no keyword"})
+			return
+		}
+	case models.MatchPrefix:
+		if keyword == "" || strings.Contains(keyword, " ") || len(keyword) > 32 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "prefix keyword must be a single word of at most 32 characters"})
+			return
+		}
+	case models.MatchExact, models.MatchContains:
+		if keyword == "" || len(keyword) > 160 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "keyword must be 1 to 160 characters"})
+			return
+		}
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "match_type must be exact, prefix, contains or any"})
+		return
+	}
+
+	if len(req.Reply) > 500 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "reply must be at most 500 characters"})
+		return
+	}
+	switch req.Action {
+	case models.ActionNone, models.ActionOptOut, models.ActionOptIn:
+		if req.Tag != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "tag is only allowed with action tag"})
+			return
+		}
+	case models.ActionTag:
+		if !tagPattern.MatchString(req.Tag) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "tag must be 1 to 64 letters, digits or _ . : -"})
+			return
+		}
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "action must be opt_out, opt_in or tag"})
+		return
+	}
+	if strings.TrimSpace(req.Reply) == "" && req.Action == models.ActionNone {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "a rule needs a reply or an action"})
+		return
+	}
+	if req.CooldownSeconds < 0 || req.CooldownSeconds > maxAutoReplyCooldown {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "cooldown_seconds must be between 0 and " + strconv.Itoa(maxAutoReplyCooldown)})
+		return
+	}
+
+	ctx := c.Request.Context()
+	number := service.NormalizeNumber(req.Number)
+	routed, err := db.HasInboundRoute(ctx, req.UserID, number)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	if !routed {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "number is not routed to this user"})
+		return
+	}
+
+	rule := &models.AutoReplyRule{
+		UserID:          req.UserID,
+		Number:          number,
+		MatchType:       req.MatchType,
+		Keyword:         keyword,
+		Reply:           strings.TrimSpace(req.Reply),
+		Action:          req.Action,
+		Tag:             req.Tag,
+		CooldownSeconds: req.CooldownSeconds,
+	}
+	if err := db.CreateAutoReplyRule(ctx, rule); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	c.JSON(http.StatusCreated, rule)
+}
+
+// @Summary List auto-reply rules
+// @Description List a user's auto-reply rules, optionally for one number.
+// @Tags Inbound
+// @Produce  json
+// @Param   user_id query string true "User ID"
+// @Param   number query string false "Routed number"
+// @Success 200 {array} models.AutoReplyRule "Rules"
+// @Failure 400 {object} map[string]interface{} "Invalid user ID"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /auto-replies [get]
+func listAutoReplyRules(c *gin.Context) {
+	userID := c.Query("user_id")
+	if _, err := uuid.Parse(userID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user_id format (must be UUID)"})
+		return
+	}
+	rules, err := db.ListAutoReplyRules(c.Request.Context(), userID, service.NormalizeNumber(c.Query("number")))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	c.JSON(http.StatusOK, rules)
+}
+
+// @Summary Delete auto-reply rule
+// @Description Stop answering with a rule. Opt-outs and tags it added are kept.
+// @Tags Inbound
+// @Produce  json
+// @Param   id path int true "Rule ID"
+// @Success 200 {object} map[string]interface{} "Rule deleted"
+// @Failure 400 {object} map[string]interface{} "Invalid rule ID"
+// @Failure 404 {object} map[string]interface{} "Rule not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /auto-replies/{id} [delete]
+func deleteAutoReplyRule(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid rule id"})
+		return
+	}
+	deleted, err := db.DeleteAutoReplyRule(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	if !deleted {
+		c.JSON(http.StatusNotFound, gin.H{"error": "rule not found"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"id": id, "deleted": true})
+}
+
+// @Summary List opt-outs
+// @Description List the numbers that opted out of a user's marketing messages. Marketing messages and campaign recipients to them are rejected.
+// @Tags Inbound
+// @Produce  json
+// @Param   user_id query string true "User ID"
+// @Success 200 {array} models.OptOut "Opted-out numbers"
+// @Failure 400 {object} map[string]interface{} "Invalid user ID"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /opt-outs [get]
+func listOptOuts(c *gin.Context) {
+	userID := c.Query("user_id")
+	if _, err := uuid.Parse(userID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user_id format (must be UUID)"})
+		return
+	}
+	optOuts, err := db.ListOptOuts(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	c.JSON(http.StatusOK, optOuts)
+}
+
+// @Summary List contact tags
+// @Description List the numbers auto-reply rules tagged for a user, optionally only one tag.
+// @Tags Inbound
+// @Produce  json
+// @Param   user_id query string true "User ID"
+// @Param   tag query string false "Tag"
+// @Success 200 {array} models.ContactTag "Tagged numbers"
+// @Failure 400 {object} map[string]interface{} "Invalid user ID"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /contact-tags [get]
+func listContactTags(c *gin.Context) {
+	userID := c.Query("user_id")
+	if _, err := uuid.Parse(userID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user_id format (must be UUID)"})
+		return
+	}
+	tags, err := db.ListContactTags(c.Request.Context(), userID, c.Query("tag"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	c.JSON(http.StatusOK, tags)
+}
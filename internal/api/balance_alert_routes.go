@@ -0,0 +1,86 @@
+package api
+
+import (
+	"arvan-sms-gateway/internal/alerts"
+	"arvan-sms-gateway/internal/config"
+	"arvan-sms-gateway/internal/db"
+	"arvan-sms-gateway/internal/models"
+	"arvan-sms-gateway/internal/webhook"
+	"database/sql"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"net/http"
+	"strings"
+)
+
+// @Summary Configure Low-Balance Alerts
+// @Description Replace the low-balance thresholds and notification channels (SMS and/or webhook) of a user. An empty thresholds list disables alerts.
+// @Tags Wallet
+// @Accept  json
+// @Produce  json
+// @Param   user_id path string true "User ID"
+// @Param   request body models.BalanceAlertConfig true "Alert configuration"
+// @Success 200 {object} map[string]interface{} "Alerts configured"
+// @Failure 400 {object} map[string]interface{} "Invalid user ID or configuration"
+// @Failure 404 {object} map[string]interface{} "User not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /balance/{user_id}/alerts [put]
+func RegisterBalanceAlertRoutes(r *gin.Engine, cfg *config.Config) {
+	r.PUT("/balance/:user_id/alerts", func(c *gin.Context) {
+		userID := c.Param("user_id")
+
+		if _, err := uuid.Parse(userID); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user_id format (must be UUID)"})
+			return
+		}
+
+		var req models.BalanceAlertConfig
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid JSON payload"})
+			return
+		}
+		if req.Hysteresis < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "hysteresis must not be negative"})
+			return
+		}
+		if len(req.Thresholds) > 10 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "at most 10 thresholds are allowed"})
+			return
+		}
+		if req.NotifyPhone != "" && len(strings.TrimSpace(req.NotifyPhone)) < 10 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid notify_phone"})
+			return
+		}
+		if req.WebhookURL != "" {
+			if err := webhook.CheckURL(c.Request.Context(), req.WebhookURL); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid webhook_url: " + err.Error()})
+				return
+			}
+		}
+
+		if _, err := db.GetUserBalance(userID); err != nil {
+			if err == sql.ErrNoRows {
+				c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+			return
+		}
+
+		if err := alerts.SaveSettings(userID, req.Thresholds, req.Hysteresis, strings.TrimSpace(req.NotifyPhone), req.WebhookURL); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+			return
+		}
+
+		settings, err := alerts.GetSettings(userID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"user_id":     userID,
+			"low_balance": settings,
+			"state":       settings.State(),
+		})
+	})
+}
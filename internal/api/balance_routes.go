@@ -1,6 +1,7 @@
 package api
 
 import (
+	"arvan-sms-gateway/internal/alerts"
 	"arvan-sms-gateway/internal/config"
 	"arvan-sms-gateway/internal/db"
 	"database/sql"
@@ -10,7 +11,7 @@ import (
 )
 
 // @Summary Get User Balance
-// @Description Retrieve the current wallet balance for a given user ID.
+// @Description Retrieve the wallet of a given user ID: the prepaid balance, usage outstanding on credit (postpaid accounts), the amount still available to spend, and the state of its low-balance alert thresholds.
 // @Tags Wallet
 // @Produce  json
 // @Param   user_id path string true "User ID"
@@ -28,7 +29,7 @@ func RegisterBalanceRoutes(r *gin.Engine, cfg *config.Config) {
 			return
 		}
 
-		balance, err := db.GetUserBalance(userID)
+		wallet, err := db.GetWallet(c.Request.Context(), userID)
 		if err != nil {
 			if err == sql.ErrNoRows {
 				c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
@@ -38,9 +39,24 @@ func RegisterBalanceRoutes(r *gin.Engine, cfg *config.Config) {
 			return
 		}
 
+		lowBalance, err := alerts.GetSettings(userID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+			return
+		}
+
 		c.JSON(http.StatusOK, gin.H{
-			"user_id": userID,
-			"balance": balance,
+			"user_id":      userID,
+			"account_type": wallet.AccountType,
+			"balance":      wallet.Prepaid(),
+			"outstanding":  wallet.Outstanding(),
+			"credit_limit": wallet.CreditLimit,
+			"available":    wallet.Available(),
+			"low_balance": gin.H{
+				"state":      lowBalance.State(),
+				"thresholds": lowBalance.Thresholds,
+				"hysteresis": lowBalance.Hysteresis,
+			},
 		})
 	})
 }
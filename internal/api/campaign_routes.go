@@ -0,0 +1,298 @@
+package api
+
+import (
+	"arvan-sms-gateway/internal/config"
+	"arvan-sms-gateway/internal/db"
+	"arvan-sms-gateway/internal/models"
+	"arvan-sms-gateway/internal/service"
+	"database/sql"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+func RegisterCampaignRoutes(r *gin.Engine, cfg *config.Config) {
+	r.POST("/campaigns", createCampaign(cfg))
+	r.GET("/campaigns/:id", getCampaign)
+	r.POST("/campaigns/:id/recipients", uploadRecipients(cfg))
+	r.GET("/campaigns/:id/estimate", estimateCampaign)
+	r.POST("/campaigns/:id/start", startCampaign)
+	r.POST("/campaigns/:id/pause", pauseCampaign)
+	r.POST("/campaigns/:id/resume", resumeCampaign)
+	r.POST("/campaigns/:id/cancel", cancelCampaign)
+}
+
+// loadCampaign writes the error response and returns nil when the :id
+// campaign cannot be loaded.
+func loadCampaign(c *gin.Context) *models.Campaign {
+	id := c.Param("id")
+	if _, err := uuid.Parse(id); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid campaign id format (must be UUID)"})
+		return nil
+	}
+	campaign, err := db.GetCampaign(c.Request.Context(), id)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "campaign not found"})
+		return nil
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return nil
+	}
+	return campaign
+}
+
+// @Summary Create campaign
+// @Description Create a draft marketing campaign. The body may reference CSV columns as {column}; rate_per_second defaults to campaign_default_rate and is capped by campaign_max_rate. With shorten_links, URLs in each message are replaced by tracked short links (see GET /campaigns/{id}/clicks).
+// @Tags Campaigns
+// @Accept  json
+// @Produce  json
+// @Param   request body models.CampaignCreateRequest true "Campaign"
+// @Success 201 {object} models.Campaign "Campaign created"
+// @Failure 400 {object} map[string]interface{} "Invalid request"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /campaigns [post]
+func createCampaign(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.CampaignCreateRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid JSON payload"})
+			return
+		}
+
+		if _, err := uuid.Parse(req.UserID); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user_id format (must be UUID)"})
+			return
+		}
+		req.Name = strings.TrimSpace(req.Name)
+		if req.Name == "" || len(req.Name) > 200 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "name must be 1 to 200 characters"})
+			return
+		}
+		if strings.TrimSpace(req.Body) == "" || len(req.Body) > 500 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid message content"})
+			return
+		}
+		if req.RatePerSecond == 0 {
+			req.RatePerSecond = cfg.CampaignDefaultRate
+		}
+		if req.RatePerSecond < 1 || req.RatePerSecond > cfg.CampaignMaxRate {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "rate_per_second must be between 1 and " + strconv.Itoa(cfg.CampaignMaxRate)})
+			return
+		}
+		if req.ShortenLinks && cfg.ShortLinkBaseURL == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": service.ErrLinksDisabled.Error()})
+			return
+		}
+
+		campaign, err := service.CreateCampaign(c.Request.Context(), req)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+			return
+		}
+		c.JSON(http.StatusCreated, campaign)
+	}
+}
+
+// @Summary Get campaign
+// @Description Return a campaign with its progress: recipients not dispatched yet and dispatched messages counted by status.
+// @Tags Campaigns
+// @Produce  json
+// @Param   id path string true "Campaign ID"
+// @Success 200 {object} map[string]interface{} "Campaign and progress"
+// @Failure 400 {object} map[string]interface{} "Invalid campaign ID"
+// @Failure 404 {object} map[string]interface{} "Campaign not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /campaigns/{id} [get]
+func getCampaign(c *gin.Context) {
+	campaign := loadCampaign(c)
+	if campaign == nil {
+		return
+	}
+	progress, err := db.GetCampaignProgress(c.Request.Context(), campaign.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"campaign": campaign, "progress": progress})
+}
+
+// @Summary Upload campaign recipients
+// @Description Add recipients to a draft campaign from a CSV with a header row, sent as the multipart field "file" or as the raw request body. One column must be phone_number, phone or mobile; every {column} in the campaign body must be present. Invalid rows are rejected and reported, duplicate phone numbers are skipped.
+// @Tags Campaigns
+// @Accept  multipart/form-data
+// @Accept  text/csv
+// @Produce  json
+// @Param   id path string true "Campaign ID"
+// @Param   file formData file false "Recipients CSV"
+// @Success 200 {object} service.ImportResult "Recipients added"
+// @Failure 400 {object} map[string]interface{} "Invalid CSV or too many rows"
+// @Failure 404 {object} map[string]interface{} "Campaign not found"
+// @Failure 409 {object} map[string]interface{} "Campaign is not a draft"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /campaigns/{id}/recipients [post]
+func uploadRecipients(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		campaign := loadCampaign(c)
+		if campaign == nil {
+			return
+		}
+
+		var body io.Reader = c.Request.Body
+		if strings.HasPrefix(c.ContentType(), "multipart/") {
+			fh, err := c.FormFile("file")
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "multipart field \"file\" is required"})
+				return
+			}
+			f, err := fh.Open()
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "cannot read uploaded file"})
+				return
+			}
+			defer f.Close()
+			body = f
+		}
+
+		result, err := service.ImportRecipients(c.Request.Context(), campaign, body, cfg.CampaignMaxRecipients)
+		switch {
+		case errors.Is(err, db.ErrCampaignNotDraft):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		case errors.Is(err, sql.ErrNoRows):
+			c.JSON(http.StatusNotFound, gin.H{"error": "campaign not found"})
+		case errors.Is(err, service.ErrInvalidCSV), errors.Is(err, service.ErrTooManyRecipients):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case err != nil:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		default:
+			c.JSON(http.StatusOK, result)
+		}
+	}
+}
+
+// @Summary Estimate campaign cost
+// @Description Price the recipients not dispatched yet and compare the cost with the user's available balance.
+// @Tags Campaigns
+// @Produce  json
+// @Param   id path string true "Campaign ID"
+// @Success 200 {object} service.CampaignEstimate "Estimate"
+// @Failure 400 {object} map[string]interface{} "Invalid campaign ID"
+// @Failure 404 {object} map[string]interface{} "Campaign not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /campaigns/{id}/estimate [get]
+func estimateCampaign(c *gin.Context) {
+	campaign := loadCampaign(c)
+	if campaign == nil {
+		return
+	}
+	estimate, err := service.EstimateCampaign(c.Request.Context(), campaign)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	c.JSON(http.StatusOK, estimate)
+}
+
+// @Summary Start campaign
+// @Description Start dispatching a draft campaign that has recipients. The user must be allowed the marketing priority class.
+// @Tags Campaigns
+// @Produce  json
+// @Param   id path string true "Campaign ID"
+// @Success 200 {object} map[string]interface{} "Campaign running"
+// @Failure 400 {object} map[string]interface{} "Campaign has no recipients"
+// @Failure 403 {object} map[string]interface{} "marketing priority not allowed for the user"
+// @Failure 404 {object} map[string]interface{} "Campaign not found"
+// @Failure 409 {object} map[string]interface{} "Campaign is not a draft"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /campaigns/{id}/start [post]
+func startCampaign(c *gin.Context) {
+	campaign := loadCampaign(c)
+	if campaign == nil {
+		return
+	}
+	ctx := c.Request.Context()
+
+	total, _, err := db.CountCampaignRecipients(ctx, campaign.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	if total == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "campaign has no recipients"})
+		return
+	}
+	allowed, err := service.CanRunCampaigns(ctx, campaign.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "user fetch error"})
+		return
+	}
+	if !allowed {
+		c.JSON(http.StatusForbidden, gin.H{"error": "priority marketing not allowed for this user"})
+		return
+	}
+
+	changeCampaignStatus(c, campaign, models.CampaignRunning, models.CampaignDraft)
+}
+
+// @Summary Pause campaign
+// @Description Stop dispatching a running campaign. Messages already dispatched are not affected.
+// @Tags Campaigns
+// @Produce  json
+// @Param   id path string true "Campaign ID"
+// @Success 200 {object} map[string]interface{} "Campaign paused"
+// @Failure 404 {object} map[string]interface{} "Campaign not found"
+// @Failure 409 {object} map[string]interface{} "Campaign is not running"
+// @Router /campaigns/{id}/pause [post]
+func pauseCampaign(c *gin.Context) {
+	if campaign := loadCampaign(c); campaign != nil {
+		changeCampaignStatus(c, campaign, models.CampaignPaused, models.CampaignRunning)
+	}
+}
+
+// @Summary Resume campaign
+// @Description Continue dispatching a paused campaign, including one paused by the dispatcher for insufficient balance.
+// @Tags Campaigns
+// @Produce  json
+// @Param   id path string true "Campaign ID"
+// @Success 200 {object} map[string]interface{} "Campaign running"
+// @Failure 404 {object} map[string]interface{} "Campaign not found"
+// @Failure 409 {object} map[string]interface{} "Campaign is not paused"
+// @Router /campaigns/{id}/resume [post]
+func resumeCampaign(c *gin.Context) {
+	if campaign := loadCampaign(c); campaign != nil {
+		changeCampaignStatus(c, campaign, models.CampaignRunning, models.CampaignPaused)
+	}
+}
+
+// @Summary Cancel campaign
+// @Description Stop a campaign for good. Recipients not dispatched yet are never sent.
+// @Tags Campaigns
+// @Produce  json
+// @Param   id path string true "Campaign ID"
+// @Success 200 {object} map[string]interface{} "Campaign cancelled"
+// @Failure 404 {object} map[string]interface{} "Campaign not found"
+// @Failure 409 {object} map[string]interface{} "Campaign already completed or cancelled"
+// @Router /campaigns/{id}/cancel [post]
+func cancelCampaign(c *gin.Context) {
+	if campaign := loadCampaign(c); campaign != nil {
+		changeCampaignStatus(c, campaign, models.CampaignCancelled,
+			models.CampaignDraft, models.CampaignRunning, models.CampaignPaused)
+	}
+}
+
+func changeCampaignStatus(c *gin.Context, campaign *models.Campaign, to models.CampaignStatus, from ...models.CampaignStatus) {
+	ok, err := db.SetCampaignStatus(c.Request.Context(), campaign.ID, to, from...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	if !ok {
+		c.JSON(http.StatusConflict, gin.H{"error": "campaign cannot move to " + string(to) + " from its current status"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"id": campaign.ID, "status": to})
+}
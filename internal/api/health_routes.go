@@ -0,0 +1,38 @@
+package api
+
+import (
+	"arvan-sms-gateway/internal/config"
+	"arvan-sms-gateway/internal/health"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+// @Summary Liveness probe
+// @Description Reports that the process is up. It does not check dependencies.
+// @Tags Health
+// @Produce  json
+// @Success 200 {object} map[string]interface{} "Process is alive"
+// @Router /healthz [get]
+func RegisterHealthRoutes(r *gin.Engine, cfg *config.Config) {
+	r.GET("/healthz", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"status": "ok"})
+	})
+
+	r.GET("/readyz", readyz)
+}
+
+// @Summary Readiness probe
+// @Description Checks Postgres, Redis, the Kafka producer and the reservation service, with the latency of each check.
+// @Tags Health
+// @Produce  json
+// @Success 200 {object} health.Report "All dependencies are reachable"
+// @Failure 503 {object} health.Report "At least one dependency failed its check"
+// @Router /readyz [get]
+func readyz(c *gin.Context) {
+	report := health.Ready(c.Request.Context())
+	if report.Status != "ok" {
+		c.JSON(http.StatusServiceUnavailable, report)
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
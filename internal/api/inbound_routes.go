@@ -0,0 +1,236 @@
+package api
+
+import (
+	"arvan-sms-gateway/internal/config"
+	"arvan-sms-gateway/internal/db"
+	"arvan-sms-gateway/internal/models"
+	"arvan-sms-gateway/internal/service"
+	"arvan-sms-gateway/internal/webhook"
+	"crypto/subtle"
+	"database/sql"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var providerPattern = regexp.MustCompile(`^[a-z0-9_-]{1,32}$`)
+
+func RegisterInboundRoutes(r *gin.Engine, cfg *config.Config) {
+	r.POST("/inbound/providers/:provider", receiveInbound(cfg))
+	r.GET("/inbound", listInbound)
+	r.POST("/inbound/routes", createInboundRoute)
+	r.GET("/inbound/routes", listInboundRoutes)
+	r.DELETE("/inbound/routes/:id", deleteInboundRoute)
+}
+
+// @Summary Receive inbound SMS (provider)
+// @Description Provider-facing endpoint for mobile-originated messages. The message is routed to a user by destination number and first word (keyword) and stored; a message_id reported twice is stored once. The configured inbound_token must be sent as X-Inbound-Token.
+// @Tags Inbound
+// @Accept  json
+// @Produce  json
+// @Param   provider path string true "Provider name"
+// @Param   X-Inbound-Token header string false "Shared provider secret"
+// @Param   request body models.ProviderInbound true "Inbound message"
+// @Success 200 {object} map[string]interface{} "Message stored (or already stored)"
+// @Failure 400 {object} map[string]interface{} "Invalid message"
+// @Failure 401 {object} map[string]interface{} "Missing or wrong token"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /inbound/providers/{provider} [post]
+func receiveInbound(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if cfg.InboundToken != "" &&
+			subtle.ConstantTimeCompare([]byte(c.GetHeader("X-Inbound-Token")), []byte(cfg.InboundToken)) != 1 {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid inbound token"})
+			return
+		}
+		provider := c.Param("provider")
+		if !providerPattern.MatchString(provider) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid provider name"})
+			return
+		}
+
+		var req models.ProviderInbound
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid JSON payload"})
+			return
+		}
+		if strings.TrimSpace(req.MessageID) == "" || len(req.MessageID) > 100 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "message_id is required"})
+			return
+		}
+		if service.NormalizeNumber(req.From) == "" || service.NormalizeNumber(req.To) == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "from and to are required"})
+			return
+		}
+		if len(req.Text) > 2000 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "text must be at most 2000 characters"})
+			return
+		}
+
+		msg, stored, err := service.ReceiveInbound(c.Request.Context(), provider, req)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+			return
+		}
+		if !stored {
+			c.JSON(http.StatusOK, gin.H{"status": "duplicate"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"status": "received", "id": msg.ID, "routed": msg.UserID != ""})
+	}
+}
+
+// @Summary List inbound SMS
+// @Description Poll the inbound messages routed to a user, oldest first. Pass the seq of the last message received as after to get the next page.
+// @Tags Inbound
+// @Produce  json
+// @Param   user_id query string true "User ID"
+// @Param   after query int false "Return messages with a larger seq"
+// @Param   limit query int false "Page size, 1 to 500 (default 100)"
+// @Success 200 {object} map[string]interface{} "Messages and the next cursor"
+// @Failure 400 {object} map[string]interface{} "Invalid parameters"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /inbound [get]
+func listInbound(c *gin.Context) {
+	userID := c.Query("user_id")
+	if _, err := uuid.Parse(userID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user_id format (must be UUID)"})
+		return
+	}
+	after, err := strconv.ParseInt(c.DefaultQuery("after", "0"), 10, 64)
+	if err != nil || after < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "after must be a non-negative integer"})
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil || limit < 1 || limit > 500 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 500"})
+		return
+	}
+
+	messages, err := db.ListInbound(c.Request.Context(), userID, after, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	next := after
+	if len(messages) > 0 {
+		next = messages[len(messages)-1].Seq
+	}
+	c.JSON(http.StatusOK, gin.H{"messages": messages, "next_after": next})
+}
+
+// @Summary Create inbound route
+// @Description Route messages sent to number, and starting with keyword if given, to a user. A route without keyword catches every message to the number that no keyword route matched. Routed messages are posted to webhook_url when set, and can always be polled with GET /inbound.
+// @Tags Inbound
+// @Accept  json
+// @Produce  json
+// @Param   request body models.InboundRouteRequest true "Route"
+// @Success 201 {object} models.InboundRoute "Route created"
+// @Failure 400 {object} map[string]interface{} "Invalid route"
+// @Failure 404 {object} map[string]interface{} "User not found"
+// @Failure 409 {object} map[string]interface{} "Number and keyword already routed"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /inbound/routes [post]
+func createInboundRoute(c *gin.Context) {
+	var req models.InboundRouteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid JSON payload"})
+		return
+	}
+	if _, err := uuid.Parse(req.UserID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user_id format (must be UUID)"})
+		return
+	}
+	number := service.NormalizeNumber(req.Number)
+	if len(number) < 3 || strings.Trim(number, "0123456789") != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid number"})
+		return
+	}
+	keyword := strings.ToUpper(strings.TrimSpace(req.Keyword))
+	if strings.ContainsAny(keyword, " \t\n") || len(keyword) > 32 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "keyword must be a single word of at most 32 characters"})
+		return
+	}
+	if req.WebhookURL != "" {
+		if err := webhook.CheckURL(c.Request.Context(), req.WebhookURL); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid webhook_url: " + err.Error()})
+			return
+		}
+	}
+
+	ctx := c.Request.Context()
+	if _, err := db.GetUser(ctx, req.UserID); err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+
+	route := &models.InboundRoute{Number: number, Keyword: keyword, UserID: req.UserID, WebhookURL: req.WebhookURL}
+	if err := db.CreateInboundRoute(ctx, route); err != nil {
+		if db.IsDuplicate(err) {
+			c.JSON(http.StatusConflict, gin.H{"error": "number and keyword are already routed"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	c.JSON(http.StatusCreated, route)
+}
+
+// @Summary List inbound routes
+// @Description List the numbers and keywords routed to a user.
+// @Tags Inbound
+// @Produce  json
+// @Param   user_id query string true "User ID"
+// @Success 200 {array} models.InboundRoute "Routes"
+// @Failure 400 {object} map[string]interface{} "Invalid user ID"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /inbound/routes [get]
+func listInboundRoutes(c *gin.Context) {
+	userID := c.Query("user_id")
+	if _, err := uuid.Parse(userID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user_id format (must be UUID)"})
+		return
+	}
+	routes, err := db.ListInboundRoutes(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	c.JSON(http.StatusOK, routes)
+}
+
+// @Summary Delete inbound route
+// @Description Stop routing a number and keyword. Messages already received keep their user.
+// @Tags Inbound
+// @Produce  json
+// @Param   id path int true "Route ID"
+// @Success 200 {object} map[string]interface{} "Route deleted"
+// @Failure 400 {object} map[string]interface{} "Invalid route ID"
+// @Failure 404 {object} map[string]interface{} "Route not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /inbound/routes/{id} [delete]
+func deleteInboundRoute(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid route id"})
+		return
+	}
+	deleted, err := db.DeleteInboundRoute(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	if !deleted {
+		c.JSON(http.StatusNotFound, gin.H{"error": "route not found"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"id": id, "deleted": true})
+}
@@ -0,0 +1,109 @@
+package api
+
+import (
+	"arvan-sms-gateway/internal/config"
+	"arvan-sms-gateway/internal/db"
+	"arvan-sms-gateway/internal/metrics"
+	"database/sql"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"net/http"
+	"regexp"
+)
+
+var linkTokenPattern = regexp.MustCompile(`^[0-9A-Za-z]{8}$`)
+
+func RegisterLinkRoutes(r *gin.Engine, cfg *config.Config) {
+	r.GET("/l/:token", followShortLink)
+	r.GET("/messages/:message_id/clicks", messageClicks)
+	r.GET("/campaigns/:id/clicks", campaignClicks)
+}
+
+// @Summary Follow short link
+// @Description Redirect a recipient to the URL a short link replaced and record the click.
+// @Tags Links
+// @Param   token path string true "Short link token"
+// @Success 302 "Redirect to the original URL"
+// @Failure 404 {object} map[string]interface{} "Unknown link"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /l/{token} [get]
+func followShortLink(c *gin.Context) {
+	token := c.Param("token")
+	if !linkTokenPattern.MatchString(token) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "link not found"})
+		return
+	}
+	userAgent := c.Request.UserAgent()
+	if len(userAgent) > 512 {
+		userAgent = userAgent[:512]
+	}
+	url, err := db.ClickShortLink(c.Request.Context(), token, c.ClientIP(), userAgent)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "link not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	metrics.LinkClicks.Inc()
+	// every click must reach us to be counted
+	c.Header("Cache-Control", "no-store")
+	c.Redirect(http.StatusFound, url)
+}
+
+// @Summary Message clicks
+// @Description Count the clicks on each short link of a message.
+// @Tags Links
+// @Produce  json
+// @Param   message_id path string true "Message ID"
+// @Success 200 {object} models.MessageClickReport "Clicks per link"
+// @Failure 400 {object} map[string]interface{} "Invalid message ID format"
+// @Failure 404 {object} map[string]interface{} "Message not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /messages/{message_id}/clicks [get]
+func messageClicks(c *gin.Context) {
+	messageID := c.Param("message_id")
+	if _, err := uuid.Parse(messageID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid message_id format (must be UUID)"})
+		return
+	}
+	ctx := c.Request.Context()
+	if _, err := db.GetMessageStatus(ctx, messageID); err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "message not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch message status"})
+		return
+	}
+	report, err := db.GetMessageClicks(ctx, messageID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// @Summary Campaign clicks
+// @Description Summarize the clicks on a campaign's short links: messages that got links, messages clicked, total clicks, click rate, and clicks per URL.
+// @Tags Links
+// @Produce  json
+// @Param   id path string true "Campaign ID"
+// @Success 200 {object} models.CampaignClickReport "Click report"
+// @Failure 400 {object} map[string]interface{} "Invalid campaign ID"
+// @Failure 404 {object} map[string]interface{} "Campaign not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /campaigns/{id}/clicks [get]
+func campaignClicks(c *gin.Context) {
+	campaign := loadCampaign(c)
+	if campaign == nil {
+		return
+	}
+	report, err := db.GetCampaignClicks(c.Request.Context(), campaign.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
@@ -3,6 +3,7 @@ package api
 import (
 	"arvan-sms-gateway/internal/config"
 	"arvan-sms-gateway/internal/db"
+	"arvan-sms-gateway/internal/models"
 	"database/sql"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -10,7 +11,7 @@ import (
 )
 
 // @Summary Get Message Status
-// @Description Retrieve the delivery status of a previously submitted SMS by its Message ID.
+// @Description Retrieve the delivery status of a previously submitted SMS by its Message ID. Deferred marketing messages also report deferred_until.
 // @Tags Messages
 // @Produce  json
 // @Param   message_id path string true "Message ID"
@@ -28,7 +29,7 @@ func RegisterMessageStatusRoutes(r *gin.Engine, cfg *config.Config) {
 			return
 		}
 
-		status, err := db.GetMessageStatus(messageID)
+		status, err := db.GetMessageStatus(c.Request.Context(), messageID)
 		if err != nil {
 			if err == sql.ErrNoRows {
 				c.JSON(http.StatusNotFound, gin.H{"error": "message not found"})
@@ -38,6 +39,20 @@ func RegisterMessageStatusRoutes(r *gin.Engine, cfg *config.Config) {
 			return
 		}
 
+		if status == string(models.StatusDeferred) {
+			until, err := db.GetDeferredUntil(c.Request.Context(), messageID)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch message status"})
+				return
+			}
+			c.JSON(http.StatusOK, gin.H{
+				"message_id":     messageID,
+				"status":         status,
+				"deferred_until": until,
+			})
+			return
+		}
+
 		c.JSON(http.StatusOK, gin.H{
 			"message_id": messageID,
 			"status":     status,
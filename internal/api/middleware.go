@@ -0,0 +1,24 @@
+package api
+
+import (
+	"time"
+
+	"arvan-sms-gateway/internal/metrics"
+	"github.com/gin-gonic/gin"
+)
+
+// RequestMetrics observes the total latency of every routed request. The
+// route pattern is used as the endpoint label so path parameters do not
+// create a series per user.
+func RequestMetrics() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		endpoint := c.FullPath()
+		if endpoint == "" {
+			return
+		}
+		metrics.RequestDuration.WithLabelValues(endpoint, "total").Observe(time.Since(start).Seconds())
+	}
+}
@@ -0,0 +1,145 @@
+package api
+
+import (
+	"arvan-sms-gateway/internal/config"
+	"arvan-sms-gateway/internal/metrics"
+	"arvan-sms-gateway/internal/models"
+	"arvan-sms-gateway/internal/otp"
+	"arvan-sms-gateway/internal/service"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+func RegisterOTPRoutes(r *gin.Engine, cfg *config.Config) {
+	r.POST("/otp/send", sendOTP(cfg))
+	r.POST("/otp/verify", verifyOTP(cfg))
+}
+
+// @Summary Send OTP
+// @Description Generate a numeric code, store its hash and send it on the otp priority path. The code is valid for ttl_seconds and a new code for the same phone number can only be requested after the resend cooldown.
+// @Tags OTP
+// @Accept  json
+// @Produce  json
+// @Param   request body models.OTPSendRequest true "OTP request"
+// @Success 200 {object} map[string]interface{} "Code sent"
+// @Failure 400 {object} map[string]interface{} "Invalid request or insufficient balance"
+// @Failure 403 {object} map[string]interface{} "otp priority not allowed for the user"
+// @Failure 429 {object} map[string]interface{} "Resend cooldown, send rate limit, or a send quota used up (code quota_exceeded)"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Failure 503 {object} map[string]interface{} "OTP store or send quotas unavailable"
+// @Router /otp/send [post]
+func sendOTP(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.OTPSendRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid JSON payload"})
+			return
+		}
+
+		if _, err := uuid.Parse(req.UserID); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user_id format (must be UUID)"})
+			return
+		}
+		req.PhoneNumber = strings.TrimSpace(req.PhoneNumber)
+		if len(req.PhoneNumber) < 10 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid phone number"})
+			return
+		}
+		if req.Length != 0 && (req.Length < 4 || req.Length > 10) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "length must be between 4 and 10"})
+			return
+		}
+		if req.TTLSeconds != 0 && (req.TTLSeconds < 30 || req.TTLSeconds > 3600) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "ttl_seconds must be between 30 and 3600"})
+			return
+		}
+		if req.Template != "" && (!strings.Contains(req.Template, "{code}") || len(req.Template) > 300) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "template must contain {code} and be at most 300 characters"})
+			return
+		}
+
+		live := config.Live()
+		if !sendLimiter.Allow(req.UserID, live.SendRateLimit, live.SendRateBurst) {
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": "rate limit exceeded, try again later"})
+			return
+		}
+
+		metrics.TotalSMSRequests.Inc()
+		result, err := service.SendOTP(c.Request.Context(), req, cfg)
+		if result.Code != "" {
+			c.JSON(result.StatusCode, gin.H{"error": result.Message, "code": result.Code})
+			return
+		}
+		if result.StatusCode == http.StatusTooManyRequests {
+			retry := int(math.Ceil(result.RetryAfter.Seconds()))
+			c.Header("Retry-After", strconv.Itoa(retry))
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": result.Message, "retry_after_seconds": retry})
+			return
+		}
+		if err != nil || result.StatusCode != http.StatusOK {
+			c.JSON(result.StatusCode, gin.H{"error": result.Message})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"status":     "pending",
+			"message_id": result.MessageID,
+			"expires_at": result.ExpiresAt,
+		})
+	}
+}
+
+// @Summary Verify OTP
+// @Description Check a code sent with /otp/send. A correct code is accepted once; after the configured number of wrong attempts the code is dropped and a new one must be requested.
+// @Tags OTP
+// @Accept  json
+// @Produce  json
+// @Param   request body models.OTPVerifyRequest true "Verification request"
+// @Success 200 {object} map[string]interface{} "Code verified"
+// @Failure 400 {object} map[string]interface{} "Invalid request or wrong code"
+// @Failure 410 {object} map[string]interface{} "No active code: expired, already used or never sent"
+// @Failure 429 {object} map[string]interface{} "Too many wrong attempts"
+// @Failure 503 {object} map[string]interface{} "OTP store unavailable"
+// @Router /otp/verify [post]
+func verifyOTP(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.OTPVerifyRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid JSON payload"})
+			return
+		}
+
+		if _, err := uuid.Parse(req.UserID); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user_id format (must be UUID)"})
+			return
+		}
+		req.PhoneNumber = strings.TrimSpace(req.PhoneNumber)
+		if len(req.PhoneNumber) < 10 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid phone number"})
+			return
+		}
+		if len(req.Code) < 4 || len(req.Code) > 10 || strings.Trim(req.Code, "0123456789") != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid code format"})
+			return
+		}
+
+		messageID, left, err := service.VerifyOTP(c.Request.Context(), req, cfg)
+		switch {
+		case errors.Is(err, otp.ErrMismatch):
+			c.JSON(http.StatusBadRequest, gin.H{"verified": false, "error": "wrong code", "attempts_left": left})
+		case errors.Is(err, otp.ErrNotFound):
+			c.JSON(http.StatusGone, gin.H{"verified": false, "error": "no active code for this phone number"})
+		case errors.Is(err, otp.ErrTooManyAttempts):
+			c.JSON(http.StatusTooManyRequests, gin.H{"verified": false, "error": "too many wrong attempts, request a new code"})
+		case err != nil:
+			c.JSON(http.StatusServiceUnavailable, gin.H{"verified": false, "error": "otp store unavailable"})
+		default:
+			c.JSON(http.StatusOK, gin.H{"verified": true, "message_id": messageID})
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package api
+
+import (
+	"arvan-sms-gateway/internal/config"
+	"arvan-sms-gateway/internal/db"
+	"arvan-sms-gateway/internal/models"
+	"arvan-sms-gateway/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"net/http"
+)
+
+func RegisterQuotaRoutes(r *gin.Engine, cfg *config.Config) {
+	r.GET("/quota", getQuota(cfg))
+	r.PUT("/quota", setQuota)
+	r.DELETE("/quota", deleteQuota)
+}
+
+// validQuotaKey validates the priority and period of a quota; an empty
+// priority covers all classes.
+func validQuotaKey(c *gin.Context, priority models.Priority, period models.QuotaPeriod) bool {
+	if priority != "" {
+		if _, ok := models.ParsePriority(string(priority)); !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid priority (must be otp, transactional or marketing, or empty for all)"})
+			return false
+		}
+	}
+	if period != models.QuotaDay && period != models.QuotaMonth {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "period must be day or month"})
+		return false
+	}
+	return true
+}
+
+// @Summary Get send quotas
+// @Description Show a user's send quotas with the messages counted in the current day or month and when each resets. Periods follow the calendar of quota_timezone.
+// @Tags Quotas
+// @Produce  json
+// @Param   user_id query string true "User ID"
+// @Success 200 {object} map[string]interface{} "Quotas and usage"
+// @Failure 400 {object} map[string]interface{} "Invalid user ID"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /quota [get]
+func getQuota(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := c.Query("user_id")
+		if _, err := uuid.Parse(userID); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user_id format (must be UUID)"})
+			return
+		}
+		usage, err := service.QuotaUsage(c.Request.Context(), userID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch quota usage"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"user_id": userID, "timezone": cfg.QuotaTimezone, "quotas": usage})
+	}
+}
+
+// @Summary Set send quota
+// @Description Create or change the cap on the messages a user may send per calendar day or month, for one priority class or, without priority, for all classes together. Sends over a quota are rejected with 429 and code quota_exceeded; campaigns wait for the quota to reset. Other gateway replicas apply a change within 30 seconds.
+// @Tags Quotas
+// @Accept  json
+// @Produce  json
+// @Param   request body models.Quota true "Quota"
+// @Success 200 {object} models.Quota "Quota set"
+// @Failure 400 {object} map[string]interface{} "Invalid quota"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /quota [put]
+func setQuota(c *gin.Context) {
+	var q models.Quota
+	if err := c.ShouldBindJSON(&q); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid JSON payload"})
+		return
+	}
+	if _, err := uuid.Parse(q.UserID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user_id format (must be UUID)"})
+		return
+	}
+	if !validQuotaKey(c, q.Priority, q.Period) {
+		return
+	}
+	if q.Limit < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must not be negative"})
+		return
+	}
+
+	if err := db.SetQuota(c.Request.Context(), q); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	service.ForgetQuotas(q.UserID)
+	c.JSON(http.StatusOK, q)
+}
+
+// @Summary Delete send quota
+// @Description Remove a user's quota for a priority class (empty for the all-classes quota) and period.
+// @Tags Quotas
+// @Produce  json
+// @Param   user_id query string true "User ID"
+// @Param   priority query string false "Priority class, empty for all classes"
+// @Param   period query string true "day or month"
+// @Success 200 {object} map[string]interface{} "Quota deleted"
+// @Failure 400 {object} map[string]interface{} "Invalid parameters"
+// @Failure 404 {object} map[string]interface{} "Quota not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /quota [delete]
+func deleteQuota(c *gin.Context) {
+	userID := c.Query("user_id")
+	if _, err := uuid.Parse(userID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user_id format (must be UUID)"})
+		return
+	}
+	priority, period := models.Priority(c.Query("priority")), models.QuotaPeriod(c.Query("period"))
+	if !validQuotaKey(c, priority, period) {
+		return
+	}
+	deleted, err := db.DeleteQuota(c.Request.Context(), userID, priority, period)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	if !deleted {
+		c.JSON(http.StatusNotFound, gin.H{"error": "quota not found"})
+		return
+	}
+	service.ForgetQuotas(userID)
+	c.JSON(http.StatusOK, gin.H{"user_id": userID, "priority": priority, "period": period, "deleted": true})
+}
@@ -6,7 +6,18 @@ import (
 )
 
 func RegisterRoutes(r *gin.Engine, cfg *config.Config) {
+	r.Use(RequestID(), RequestMetrics())
+
 	RegisterSMSRoutes(r, cfg)
+	RegisterOTPRoutes(r, cfg)
+	RegisterCampaignRoutes(r, cfg)
+	RegisterInboundRoutes(r, cfg)
+	RegisterAutoReplyRoutes(r, cfg)
+	RegisterQuotaRoutes(r, cfg)
 	RegisterBalanceRoutes(r, cfg)
+	RegisterBalanceAlertRoutes(r, cfg)
 	RegisterMessageStatusRoutes(r, cfg)
+	RegisterMessageEventsRoutes(r, cfg)
+	RegisterLinkRoutes(r, cfg)
+	RegisterHealthRoutes(r, cfg)
 }
@@ -2,24 +2,41 @@ package api
 
 import (
 	"arvan-sms-gateway/internal/config"
+	"arvan-sms-gateway/internal/metrics"
 	"arvan-sms-gateway/internal/models"
+	"arvan-sms-gateway/internal/ratelimit"
 	"arvan-sms-gateway/internal/service"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"net/http"
+	"regexp"
+	"strconv"
 	"strings"
+	"time"
 )
 
+// maxValidity caps a request's validity, in seconds (72h).
+const maxValidity = 72 * 60 * 60
+
+// senderIDPattern accepts an alphanumeric sender of up to 11 characters or a
+// number of up to 15 digits.
+var senderIDPattern = regexp.MustCompile(`^([0-9A-Za-z]{1,11}|\+?[0-9]{3,15})$`)
+
+// sendLimiter enforces the per-user send_rate_limit of this replica.
+var sendLimiter = ratelimit.NewPerKey()
+
 // @Summary Send SMS
-// @Description Queue an SMS for delivery (via Kafka). Validates user, balance, phone number, and message size.
+// @Description Queue an SMS for delivery (via Kafka). Validates user, balance, phone number, sender ID, and message size. The optional priority (otp, transactional, marketing) selects the Kafka topic and must be allowed for the user. Marketing messages accepted during quiet hours are deferred until they end; those to numbers that opted out are rejected. A message not sent within its validity (validity seconds, expires_at, or the priority's default) is expired and refunded. With shorten_links, URLs in the message are replaced by tracked short links.
 // @Tags SMS
 // @Accept  json
 // @Produce  json
 // @Param   request body models.SMSRequest true "SMS Request"
 // @Success 200 {object} map[string]interface{} "Message queued successfully"
-// @Failure 400 {object} map[string]interface{} "Invalid request, invalid UUID, phone, or message size"
-// @Failure 429 {object} map[string]interface{} "Server busy, try again later"
+// @Failure 400 {object} map[string]interface{} "Invalid request, invalid UUID, phone, message size or priority, or insufficient balance"
+// @Failure 403 {object} map[string]interface{} "Priority class not allowed for the user, or recipient opted out"
+// @Failure 429 {object} map[string]interface{} "Per-user send rate limit exceeded, or a send quota used up (code quota_exceeded)"
 // @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Failure 503 {object} map[string]interface{} "Send quotas cannot be checked (unless quota_fail_open is set)"
 // @Router /send-sms [post]
 func RegisterSMSRoutes(r *gin.Engine, cfg *config.Config) {
 	r.POST("/send-sms", func(c *gin.Context) {
@@ -49,12 +66,54 @@ func RegisterSMSRoutes(r *gin.Engine, cfg *config.Config) {
 			return
 		}
 
-		result, err := service.ProcessSMSRequest(req, cfg)
-		if err != nil {
+		if req.SenderID != "" && !senderIDPattern.MatchString(req.SenderID) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid sender_id (up to 11 letters and digits, or a number)"})
+			return
+		}
+
+		if _, ok := models.ParsePriority(string(req.Priority)); !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid priority (must be otp, transactional or marketing)"})
+			return
+		}
+
+		if req.Validity != 0 && req.ExpiresAt != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "set either validity or expires_at, not both"})
+			return
+		}
+		if req.Validity < 0 || req.Validity > maxValidity {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "validity must be between 1 and " + strconv.Itoa(maxValidity) + " seconds"})
+			return
+		}
+		if req.ExpiresAt != nil && (!req.ExpiresAt.After(time.Now()) || time.Until(*req.ExpiresAt) > maxValidity*time.Second) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "expires_at must be in the future and at most 72h ahead"})
+			return
+		}
+
+		live := config.Live()
+		if !sendLimiter.Allow(req.UserID, live.SendRateLimit, live.SendRateBurst) {
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": "rate limit exceeded, try again later"})
+			return
+		}
+
+		metrics.TotalSMSRequests.Inc()
+		result, err := service.ProcessSMSRequest(c.Request.Context(), req, cfg)
+		if err != nil || result.StatusCode != http.StatusOK {
+			if result.Code != "" {
+				c.JSON(result.StatusCode, gin.H{"error": result.Message, "code": result.Code})
+				return
+			}
 			c.JSON(result.StatusCode, gin.H{"error": result.Message})
 			return
 		}
 
+		if !result.DeferredUntil.IsZero() {
+			c.JSON(http.StatusOK, gin.H{
+				"status":         "deferred",
+				"message_id":     req.MessageID,
+				"deferred_until": result.DeferredUntil,
+			})
+			return
+		}
 		c.JSON(http.StatusOK, gin.H{
 			"status":     "pending",
 			"message_id": req.MessageID,
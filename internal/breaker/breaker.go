@@ -0,0 +1,168 @@
+package breaker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"arvan-sms-gateway/internal/logger"
+	"arvan-sms-gateway/internal/metrics"
+	"go.uber.org/zap"
+)
+
+var ErrOpen = errors.New("circuit breaker open")
+
+type State int
+
+const (
+	Closed State = iota
+	Open
+	HalfOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case Open:
+		return "open"
+	case HalfOpen:
+		return "half-open"
+	default:
+		return "closed"
+	}
+}
+
+type Settings struct {
+	Failures int           // consecutive failures that open the breaker
+	Cooldown time.Duration // how long it stays open before a trial call
+	Timeout  time.Duration // deadline of each call made through Do, 0 for none
+}
+
+// Breaker stops calls to a dependency after Failures consecutive failures.
+// Once Cooldown has passed a single trial call is let through (half-open):
+// its success closes the breaker again, its failure reopens it.
+type Breaker struct {
+	name     string
+	settings Settings
+
+	mu       sync.Mutex
+	state    State
+	failures int
+	openedAt time.Time
+	probing  bool // the half-open trial call is in flight
+}
+
+func newBreaker(name string, s Settings) *Breaker {
+	b := &Breaker{name: name, settings: s}
+	metrics.CircuitBreakerState.WithLabelValues(name).Set(float64(Closed))
+	return b
+}
+
+func (b *Breaker) Name() string {
+	return b.name
+}
+
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
+
+// NextTrial is when an open breaker lets the next call through.
+func (b *Breaker) NextTrial() time.Time {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state != Open {
+		return time.Now()
+	}
+	return b.openedAt.Add(b.settings.Cooldown)
+}
+
+// Allow returns ErrOpen when the call must not be made. Otherwise the caller
+// reports the outcome with Record, or calls Release if it never made the call.
+func (b *Breaker) Allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case Open:
+		if time.Since(b.openedAt) < b.settings.Cooldown {
+			return b.reject()
+		}
+		b.setState(HalfOpen)
+	case HalfOpen:
+		if b.probing {
+			return b.reject()
+		}
+	default:
+		return nil
+	}
+	b.probing = true
+	return nil
+}
+
+// Record reports the outcome of a call that Allow let through.
+func (b *Breaker) Record(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+	if err == nil {
+		b.failures = 0
+		b.setState(Closed)
+		return
+	}
+	b.failures++
+	if b.state == HalfOpen || b.failures >= b.settings.Failures {
+		b.openedAt = time.Now()
+		b.setState(Open)
+	}
+}
+
+// Release gives back a call that Allow let through without making it.
+func (b *Breaker) Release() {
+	b.mu.Lock()
+	b.probing = false
+	b.mu.Unlock()
+}
+
+// Do runs fn through the breaker with the breaker's timeout. A call the
+// caller gave up on (ctx done) says nothing about the dependency and is not
+// counted.
+func (b *Breaker) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	if err := b.Allow(); err != nil {
+		return err
+	}
+	callCtx := ctx
+	if b.settings.Timeout > 0 {
+		var cancel context.CancelFunc
+		callCtx, cancel = context.WithTimeout(ctx, b.settings.Timeout)
+		defer cancel()
+	}
+	err := fn(callCtx)
+	if err != nil && ctx.Err() != nil {
+		b.Release()
+		return err
+	}
+	b.Record(err)
+	return err
+}
+
+func (b *Breaker) reject() error {
+	metrics.CircuitBreakerRejected.WithLabelValues(b.name).Inc()
+	return fmt.Errorf("%s: %w", b.name, ErrOpen)
+}
+
+func (b *Breaker) setState(s State) {
+	if b.state == s {
+		return
+	}
+	logger.Warn("Circuit breaker state changed",
+		zap.String("breaker", b.name),
+		zap.String("from", b.state.String()),
+		zap.String("to", s.String()),
+		zap.Int("failures", b.failures))
+	b.state = s
+	metrics.CircuitBreakerState.WithLabelValues(b.name).Set(float64(s))
+}
@@ -0,0 +1,121 @@
+package breaker
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+var errDown = errors.New("down")
+
+func TestBreakerOpensAfterConsecutiveFailures(t *testing.T) {
+	b := newBreaker("test_open", Settings{Failures: 3, Cooldown: time.Hour})
+	for i := 0; i < 2; i++ {
+		if err := b.Allow(); err != nil {
+			t.Fatalf("call %d rejected: %v", i, err)
+		}
+		b.Record(errDown)
+	}
+	if b.State() != Closed {
+		t.Fatalf("state = %s after 2 failures, want closed", b.State())
+	}
+
+	// a success in between resets the count
+	b.Allow()
+	b.Record(nil)
+	for i := 0; i < 2; i++ {
+		b.Allow()
+		b.Record(errDown)
+	}
+	if b.State() != Closed {
+		t.Fatalf("state = %s, want closed: failures were not consecutive", b.State())
+	}
+
+	b.Allow()
+	b.Record(errDown)
+	if b.State() != Open {
+		t.Fatalf("state = %s after 3 consecutive failures, want open", b.State())
+	}
+	if err := b.Allow(); !errors.Is(err, ErrOpen) {
+		t.Fatalf("Allow on open breaker = %v, want ErrOpen", err)
+	}
+	if until := time.Until(b.NextTrial()); until < 59*time.Minute {
+		t.Fatalf("next trial in %s, want about the cooldown", until)
+	}
+}
+
+func TestBreakerHalfOpenTrial(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		trial error
+		want  State
+	}{
+		{"success closes", nil, Closed},
+		{"failure reopens", errDown, Open},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			b := newBreaker("test_half_open", Settings{Failures: 1, Cooldown: time.Millisecond})
+			b.Allow()
+			b.Record(errDown)
+			time.Sleep(2 * time.Millisecond)
+
+			if err := b.Allow(); err != nil {
+				t.Fatalf("trial rejected after cooldown: %v", err)
+			}
+			if b.State() != HalfOpen {
+				t.Fatalf("state = %s during trial, want half-open", b.State())
+			}
+			if err := b.Allow(); !errors.Is(err, ErrOpen) {
+				t.Fatalf("second call during trial = %v, want ErrOpen", err)
+			}
+
+			b.Record(tc.trial)
+			if b.State() != tc.want {
+				t.Fatalf("state = %s after trial, want %s", b.State(), tc.want)
+			}
+		})
+	}
+}
+
+func TestBreakerReleaseFreesTrial(t *testing.T) {
+	b := newBreaker("test_release", Settings{Failures: 1, Cooldown: time.Millisecond})
+	b.Allow()
+	b.Record(errDown)
+	time.Sleep(2 * time.Millisecond)
+
+	b.Allow()
+	b.Release()
+	if err := b.Allow(); err != nil {
+		t.Fatalf("trial not available again after Release: %v", err)
+	}
+}
+
+func TestBreakerDoIgnoresCallerCancel(t *testing.T) {
+	b := newBreaker("test_do", Settings{Failures: 1, Cooldown: time.Hour})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := b.Do(ctx, func(ctx context.Context) error { return ctx.Err() })
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Do = %v, want context.Canceled", err)
+	}
+	if b.State() != Closed {
+		t.Fatalf("state = %s, a canceled call must not count as a failure", b.State())
+	}
+
+	err = b.Do(context.Background(), func(context.Context) error { return errDown })
+	if !errors.Is(err, errDown) || b.State() != Open {
+		t.Fatalf("Do = %v, state %s; want the failure returned and the breaker open", err, b.State())
+	}
+}
+
+func TestBreakerDoTimeout(t *testing.T) {
+	b := newBreaker("test_timeout", Settings{Failures: 5, Cooldown: time.Hour, Timeout: time.Millisecond})
+	err := b.Do(context.Background(), func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Do = %v, want the call's deadline to expire", err)
+	}
+}
@@ -0,0 +1,53 @@
+package breaker
+
+import (
+	"sync"
+	"time"
+
+	"arvan-sms-gateway/internal/config"
+)
+
+var (
+	mu       sync.Mutex
+	breakers = map[string]*Breaker{}
+
+	redisSettings    = Settings{Failures: 5, Cooldown: 10 * time.Second, Timeout: 250 * time.Millisecond}
+	kafkaSettings    = Settings{Failures: 5, Cooldown: 15 * time.Second}
+	providerSettings = Settings{Failures: 5, Cooldown: 30 * time.Second}
+)
+
+// Init sets the thresholds of the process's breakers. Call it before the
+// first Redis, Kafka or provider call.
+func Init(cfg *config.Config) {
+	mu.Lock()
+	defer mu.Unlock()
+	redisSettings = Settings{Failures: cfg.RedisBreakerFailures, Cooldown: cfg.RedisBreakerCooldown, Timeout: cfg.RedisTimeout}
+	kafkaSettings = Settings{Failures: cfg.KafkaBreakerFailures, Cooldown: cfg.KafkaBreakerCooldown}
+	providerSettings = Settings{Failures: cfg.ProviderBreakerFailures, Cooldown: cfg.ProviderBreakerCooldown}
+}
+
+// Redis guards every Redis client of the process.
+func Redis() *Breaker {
+	return get("redis", &redisSettings)
+}
+
+// Kafka guards the Kafka producer.
+func Kafka() *Breaker {
+	return get("kafka", &kafkaSettings)
+}
+
+// Provider guards one SMS provider.
+func Provider(name string) *Breaker {
+	return get("provider_"+name, &providerSettings)
+}
+
+func get(name string, s *Settings) *Breaker {
+	mu.Lock()
+	defer mu.Unlock()
+	b, ok := breakers[name]
+	if !ok {
+		b = newBreaker(name, *s)
+		breakers[name] = b
+	}
+	return b
+}
@@ -3,67 +3,46 @@ package cache
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"time"
 
+	"arvan-sms-gateway/internal/breaker"
 	"arvan-sms-gateway/internal/logger"
+	"arvan-sms-gateway/internal/tracing"
 	"github.com/redis/go-redis/v9"
 	"go.uber.org/zap"
 )
 
-var ctx = context.Background()
 var rdb *redis.Client
 
 type UserData struct {
-	IsVIP   bool  `json:"is_vip"`
-	Balance int64 `json:"balance"`
+	IsVIP      bool     `json:"is_vip"`
+	Balance    int64    `json:"balance"`
+	Priorities []string `json:"priorities"`
 }
 
 func InitRedis(addr string) {
-	rdb = redis.NewClient(&redis.Options{Addr: addr})
-	if err := rdb.Ping(ctx).Err(); err != nil {
+	rdb = redis.NewClient(&redis.Options{Addr: addr, ContextTimeoutEnabled: true})
+	tracing.InstrumentRedis(rdb)
+	if err := rdb.Ping(context.Background()).Err(); err != nil {
 		logger.Error("Failed to connect Redis", zap.Error(err))
 		panic(err)
 	}
 	logger.Info("Connected to Redis")
 }
 
-// ------------------ Reservation ------------------
-
-func SetReservation(userID string, amount int64, ttl time.Duration) {
-	if rdb != nil {
-		rdb.Set(ctx, "resv:"+userID, amount, ttl)
-	}
-}
-
-func GetReservation(userID string) int64 {
-	if rdb == nil {
-		return 0
-	}
-	val, _ := rdb.Get(ctx, "resv:"+userID).Int64()
-	return val
-}
-
-func DecrementReservation(userID string, cost int64) bool {
+func Ping(ctx context.Context) error {
 	if rdb == nil {
-		return false
-	}
-	val, _ := rdb.Get(ctx, "resv:"+userID).Int64()
-	if val < cost {
-		return false
-	}
-	rdb.DecrBy(ctx, "resv:"+userID, cost)
-	return true
-}
-
-func DeleteReservation(userID string) {
-	if rdb != nil {
-		rdb.Del(ctx, "resv:"+userID)
+		return errors.New("redis not initialized")
 	}
+	return rdb.Ping(ctx).Err()
 }
 
 // ------------------ User Cache ------------------
+// Lookups go through the Redis breaker; while it is open callers fall back
+// to Postgres.
 
-func SetUser(userID string, data *UserData, ttl time.Duration) error {
+func SetUser(ctx context.Context, userID string, data *UserData, ttl time.Duration) error {
 	if rdb == nil {
 		return nil
 	}
@@ -71,18 +50,27 @@ func SetUser(userID string, data *UserData, ttl time.Duration) error {
 	if err != nil {
 		return err
 	}
-	return rdb.Set(ctx, "user:"+userID, jsonData, ttl).Err()
+	return breaker.Redis().Do(ctx, func(ctx context.Context) error {
+		return rdb.Set(ctx, "user:"+userID, jsonData, ttl).Err()
+	})
 }
 
-func GetUser(userID string) (*UserData, error) {
+func GetUser(ctx context.Context, userID string) (*UserData, error) {
 	if rdb == nil {
 		return nil, nil
 	}
-	val, err := rdb.Get(ctx, "user:"+userID).Result()
-	if err == redis.Nil {
-		return nil, nil
-	}
-	if err != nil {
+	var val string
+	found := true
+	err := breaker.Redis().Do(ctx, func(ctx context.Context) error {
+		var err error
+		val, err = rdb.Get(ctx, "user:"+userID).Result()
+		if err == redis.Nil {
+			found = false
+			return nil
+		}
+		return err
+	})
+	if err != nil || !found {
 		return nil, err
 	}
 	var data UserData
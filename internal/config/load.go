@@ -0,0 +1,396 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"arvan-sms-gateway/internal/quiethours"
+	"arvan-sms-gateway/internal/ratelimit"
+	"github.com/google/uuid"
+	"go.uber.org/zap/zapcore"
+	"gopkg.in/yaml.v3"
+)
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// setting is one leaf field of Config with the names it is known by.
+type setting struct {
+	key   string   // yaml key
+	env   []string // environment variables, first one set wins
+	value reflect.Value
+	// static is false for fields of Reloadable
+	static bool
+}
+
+func settings(cfg *Config) []setting {
+	var out []setting
+	var walk func(v reflect.Value, static bool)
+	walk = func(v reflect.Value, static bool) {
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if f.Anonymous {
+				walk(v.Field(i), f.Type != reflect.TypeOf(Reloadable{}))
+				continue
+			}
+			out = append(out, setting{
+				key:    f.Tag.Get("yaml"),
+				env:    strings.Split(f.Tag.Get("env"), ","),
+				value:  v.Field(i),
+				static: static,
+			})
+		}
+	}
+	walk(reflect.ValueOf(cfg).Elem(), true)
+	return out
+}
+
+func flagName(key string) string {
+	return strings.ReplaceAll(key, "_", "-")
+}
+
+// parse stores raw into the field, accepting only well-formed values.
+// Durations take Go syntax ("90s", "5m"); a bare integer means seconds, as
+// the interval variables did before they were typed.
+func parse(v reflect.Value, raw string) error {
+	raw = strings.TrimSpace(raw)
+	switch {
+	case v.Type() == durationType:
+		if secs, err := strconv.ParseInt(raw, 10, 64); err == nil {
+			v.SetInt(int64(time.Duration(secs) * time.Second))
+			return nil
+		}
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q (use e.g. 30s, 5m)", raw)
+		}
+		v.SetInt(int64(d))
+	case v.Kind() == reflect.String:
+		v.SetString(raw)
+	case v.Kind() == reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q (use true or false)", raw)
+		}
+		v.SetBool(b)
+	case v.Kind() == reflect.Int:
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", raw)
+		}
+		v.SetInt(int64(n))
+	case v.Kind() == reflect.Float64:
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return fmt.Errorf("invalid number %q", raw)
+		}
+		v.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported config type %s", v.Type())
+	}
+	return nil
+}
+
+// Load builds the configuration from defaults, the YAML file named by the
+// -config flag or CONFIG_FILE, the environment and the flags in args, then
+// validates it. Every problem is reported, not just the first one.
+func Load(args []string) (*Config, error) {
+	cfg := defaults()
+	all := settings(cfg)
+	byKey := make(map[string]setting, len(all))
+	for _, s := range all {
+		byKey[s.key] = s
+	}
+	source := map[string]string{}
+	var problems []string
+
+	fs := flag.NewFlagSet("config", flag.ContinueOnError)
+	path := fs.String("config", os.Getenv("CONFIG_FILE"), "YAML configuration file")
+	flags := map[string]string{}
+	for _, s := range all {
+		key := s.key
+		usage := fmt.Sprintf("overrides %s (env %s)", key, s.env[0])
+		if s.value.Kind() == reflect.Bool {
+			fs.BoolFunc(flagName(key), usage, func(v string) error { flags[key] = v; return nil })
+		} else {
+			fs.Func(flagName(key), usage, func(v string) error { flags[key] = v; return nil })
+		}
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	if *path != "" {
+		body, err := os.ReadFile(*path)
+		if err != nil {
+			return nil, fmt.Errorf("config file: %w", err)
+		}
+		var doc map[string]any
+		if err := yaml.Unmarshal(body, &doc); err != nil {
+			return nil, fmt.Errorf("config file %s: %w", *path, err)
+		}
+		for key, raw := range doc {
+			s, ok := byKey[key]
+			if !ok {
+				problems = append(problems, fmt.Sprintf("%s: unknown key %q", *path, key))
+				continue
+			}
+			switch raw.(type) {
+			case map[string]any, []any:
+				problems = append(problems, fmt.Sprintf("%s: %s: expected a single value", *path, key))
+				continue
+			case nil:
+				continue
+			}
+			if err := parse(s.value, fmt.Sprint(raw)); err != nil {
+				problems = append(problems, fmt.Sprintf("%s: %s: %v", *path, key, err))
+				continue
+			}
+			source[key] = *path
+		}
+	}
+
+	for _, s := range all {
+		for _, name := range s.env {
+			raw, ok := os.LookupEnv(name)
+			if !ok {
+				continue
+			}
+			if err := parse(s.value, raw); err != nil {
+				problems = append(problems, fmt.Sprintf("env %s: %v", name, err))
+			} else {
+				source[s.key] = "env " + name
+			}
+			break
+		}
+	}
+
+	for key, raw := range flags {
+		if err := parse(byKey[key].value, raw); err != nil {
+			problems = append(problems, fmt.Sprintf("flag -%s: %v", flagName(key), err))
+			continue
+		}
+		source[key] = "flag -" + flagName(key)
+	}
+
+	for _, p := range cfg.validate() {
+		if src, ok := source[p.key]; ok {
+			problems = append(problems, fmt.Sprintf("%s: %s (from %s)", p.key, p.msg, src))
+		} else {
+			problems = append(problems, fmt.Sprintf("%s: %s", p.key, p.msg))
+		}
+	}
+	if len(problems) > 0 {
+		return nil, errors.New("invalid configuration:\n  " + strings.Join(problems, "\n  "))
+	}
+	configPath = *path
+	return cfg, nil
+}
+
+type problem struct {
+	key, msg string
+}
+
+func (c *Config) validate() []problem {
+	var out []problem
+	bad := func(key, format string, args ...any) {
+		out = append(out, problem{key, fmt.Sprintf(format, args...)})
+	}
+
+	required := [][2]string{
+		{"service_name", c.ServiceName},
+		{"db_url", c.DBUrl},
+		{"redis_addr", c.RedisAddr},
+		{"kafka_brokers", c.KafkaBrokers},
+		{"kafka_group_normal", c.KafkaGroupNormal},
+		{"kafka_group_vip", c.KafkaGroupVIP},
+		{"kafka_topic_normal", c.KafkaTopicNormal},
+		{"kafka_topic_vip", c.KafkaTopicVIP},
+	}
+	for _, kv := range required {
+		if strings.TrimSpace(kv[1]) == "" {
+			bad(kv[0], "must not be empty")
+		}
+	}
+
+	ports := [][2]string{
+		{"server_port", c.ServerPort},
+		{"server_metrics_port", c.ServerMetricsPort},
+		{"worker_normal_metrics_port", c.NormalMetricsPort},
+		{"worker_vip_metrics_port", c.VIPMetricsPort},
+		{"worker_health_port", c.WorkerHealthPort},
+		{"worker_vip_health_port", c.VIPHealthPort},
+	}
+	for _, kv := range ports {
+		if n, err := strconv.Atoi(kv[1]); err != nil || n < 1 || n > 65535 {
+			bad(kv[0], "must be a port number, got %q", kv[1])
+		}
+	}
+
+	// the same names queue.ParseInitialOffset and kafka-offsets accept
+	switch c.KafkaInitialOffset {
+	case "oldest", "earliest", "newest", "latest":
+	default:
+		bad("kafka_initial_offset", `must be "oldest" (or "earliest") or "newest" (or "latest"), got %q`, c.KafkaInitialOffset)
+	}
+	if c.ReservationTTL <= 0 {
+		bad("reservation_ttl", "must be positive")
+	}
+	if c.RefundInterval < 0 {
+		bad("refund_job_interval", "must not be negative")
+	}
+	if c.ReconcileInterval < 0 {
+		bad("wallet_reconcile_interval", "must not be negative")
+	}
+	if c.RefundBatchSize < 1 {
+		bad("refund_batch_size", "must be at least 1, got %d", c.RefundBatchSize)
+	}
+	if c.AlertSenderUserID != "" {
+		if _, err := uuid.Parse(c.AlertSenderUserID); err != nil {
+			bad("alert_sender_user_id", "must be a user UUID, got %q", c.AlertSenderUserID)
+		}
+	}
+
+	switch c.TracingExporter {
+	case "none", "otlp", "stdout":
+	default:
+		bad("tracing_exporter", `must be "none", "otlp" or "stdout", got %q`, c.TracingExporter)
+	}
+	if c.TracingEndpoint != "" {
+		if u, err := url.Parse(c.TracingEndpoint); err != nil || u.Scheme == "" || u.Host == "" {
+			bad("tracing_otlp_endpoint", "must be an absolute URL, got %q", c.TracingEndpoint)
+		}
+	}
+	if c.TracingSamplePct < 0 || c.TracingSamplePct > 100 {
+		bad("tracing_sample_percent", "must be between 0 and 100, got %d", c.TracingSamplePct)
+	}
+
+	if c.RedisTimeout < 0 {
+		bad("redis_timeout", "must not be negative")
+	}
+	if c.ParkedRetryInterval < 0 {
+		bad("parked_retry_interval", "must not be negative")
+	}
+	if c.ClaimLease <= 0 {
+		bad("claim_lease", "must be positive")
+	}
+	breakers := []struct {
+		name     string
+		failures int
+		cooldown time.Duration
+	}{
+		{"redis", c.RedisBreakerFailures, c.RedisBreakerCooldown},
+		{"kafka", c.KafkaBreakerFailures, c.KafkaBreakerCooldown},
+		{"provider", c.ProviderBreakerFailures, c.ProviderBreakerCooldown},
+	}
+	for _, br := range breakers {
+		if br.failures < 1 {
+			bad(br.name+"_breaker_failures", "must be at least 1, got %d", br.failures)
+		}
+		if br.cooldown <= 0 {
+			bad(br.name+"_breaker_cooldown", "must be positive")
+		}
+	}
+
+	if c.WorkerConcurrency < 1 {
+		bad("worker_concurrency", "must be at least 1, got %d", c.WorkerConcurrency)
+	}
+	weights := []struct {
+		key   string
+		value int
+	}{
+		{"priority_weight_otp", c.PriorityWeightOTP},
+		{"priority_weight_transactional", c.PriorityWeightTransactional},
+		{"priority_weight_marketing", c.PriorityWeightMarketing},
+	}
+	for _, w := range weights {
+		if w.value < 1 {
+			bad(w.key, "must be at least 1, got %d", w.value)
+		}
+	}
+
+	if c.OTPLength < 4 || c.OTPLength > 10 {
+		bad("otp_length", "must be between 4 and 10, got %d", c.OTPLength)
+	}
+	if c.OTPTTL <= 0 {
+		bad("otp_ttl", "must be positive")
+	}
+	if c.OTPMaxAttempts < 1 {
+		bad("otp_max_attempts", "must be at least 1, got %d", c.OTPMaxAttempts)
+	}
+	if c.OTPResendCooldown < 0 {
+		bad("otp_resend_cooldown", "must not be negative")
+	}
+	if !strings.Contains(c.OTPTemplate, "{code}") {
+		bad("otp_template", "must contain {code}, got %q", c.OTPTemplate)
+	}
+
+	if c.CampaignMaxRate < 1 {
+		bad("campaign_max_rate", "must be at least 1, got %d", c.CampaignMaxRate)
+	}
+	if c.CampaignDefaultRate < 1 || c.CampaignDefaultRate > c.CampaignMaxRate {
+		bad("campaign_default_rate", "must be between 1 and campaign_max_rate, got %d", c.CampaignDefaultRate)
+	}
+	if c.CampaignMaxRecipients < 1 {
+		bad("campaign_max_recipients", "must be at least 1, got %d", c.CampaignMaxRecipients)
+	}
+	validities := []struct {
+		key   string
+		value time.Duration
+	}{
+		{"validity_otp", c.ValidityOTP},
+		{"validity_transactional", c.ValidityTransactional},
+		{"validity_marketing", c.ValidityMarketing},
+	}
+	for _, v := range validities {
+		if v.value < 0 {
+			bad(v.key, "must not be negative")
+		}
+	}
+	if c.InboundWebhookInterval < 0 {
+		bad("inbound_webhook_interval", "must not be negative")
+	}
+	if c.InboundWebhookMaxAttempts < 1 {
+		bad("inbound_webhook_max_attempts", "must be at least 1, got %d", c.InboundWebhookMaxAttempts)
+	}
+	if _, err := time.LoadLocation(c.QuotaTimezone); err != nil || c.QuotaTimezone == "" {
+		bad("quota_timezone", "unknown timezone %q", c.QuotaTimezone)
+	}
+	if c.ProviderThrottleMaxWait < 0 {
+		bad("provider_throttle_max_wait", "must not be negative")
+	}
+	if c.ShortLinkBaseURL != "" {
+		if u, err := url.Parse(c.ShortLinkBaseURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			bad("short_link_base_url", "must be an absolute http(s) URL, got %q", c.ShortLinkBaseURL)
+		}
+	}
+	if _, err := quiethours.Parse(c.QuietHours, c.QuietHoursTimezone, c.QuietHoursOverrides); err != nil {
+		bad("quiet_hours", "%v", err)
+	}
+
+	return append(out, c.Reloadable.validate()...)
+}
+
+func (r *Reloadable) validate() []problem {
+	var out []problem
+	if _, err := zapcore.ParseLevel(r.LogLevel); err != nil || r.LogLevel == "" {
+		out = append(out, problem{"log_level", fmt.Sprintf("must be debug, info, warn or error, got %q", r.LogLevel)})
+	}
+	if r.SendRateLimit < 0 {
+		out = append(out, problem{"send_rate_limit", "must not be negative"})
+	}
+	if r.SendRateLimit > 0 && r.SendRateBurst < 1 {
+		out = append(out, problem{"send_rate_burst", fmt.Sprintf("must be at least 1 when send_rate_limit is set, got %d", r.SendRateBurst)})
+	}
+	if _, err := ratelimit.ParseLimits(r.ProviderRateLimits); err != nil {
+		out = append(out, problem{"provider_rate_limits", err.Error()})
+	}
+	return out
+}
@@ -0,0 +1,132 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func problemKeys(c *Config) []string {
+	var keys []string
+	for _, p := range c.validate() {
+		keys = append(keys, p.key)
+	}
+	return keys
+}
+
+func TestDefaultsAreValid(t *testing.T) {
+	if keys := problemKeys(defaults()); len(keys) > 0 {
+		t.Fatalf("defaults fail validation: %v", keys)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		mutate func(*Config)
+		key    string // "" when the config must stay valid
+	}{
+		{"missing db_url", func(c *Config) { c.DBUrl = " " }, "db_url"},
+		{"missing topic", func(c *Config) { c.KafkaTopicVIP = "" }, "kafka_topic_vip"},
+		{"bad port", func(c *Config) { c.ServerPort = "http" }, "server_port"},
+		{"bad worker metrics port", func(c *Config) { c.VIPMetricsPort = "" }, "worker_vip_metrics_port"},
+		{"port out of range", func(c *Config) { c.WorkerHealthPort = "70000" }, "worker_health_port"},
+		{"initial offset oldest", func(c *Config) { c.KafkaInitialOffset = "oldest" }, ""},
+		{"initial offset earliest", func(c *Config) { c.KafkaInitialOffset = "earliest" }, ""},
+		{"initial offset latest", func(c *Config) { c.KafkaInitialOffset = "latest" }, ""},
+		{"initial offset unknown", func(c *Config) { c.KafkaInitialOffset = "middle" }, "kafka_initial_offset"},
+		{"reservation ttl zero", func(c *Config) { c.ReservationTTL = 0 }, "reservation_ttl"},
+		{"claim lease zero", func(c *Config) { c.ClaimLease = 0 }, "claim_lease"},
+		{"otp cooldown disabled", func(c *Config) { c.OTPResendCooldown = 0 }, ""},
+		{"negative otp cooldown", func(c *Config) { c.OTPResendCooldown = -time.Second }, "otp_resend_cooldown"},
+		{"negative throttle wait", func(c *Config) { c.ProviderThrottleMaxWait = -time.Second }, "provider_throttle_max_wait"},
+		{"quota timezone", func(c *Config) { c.QuotaTimezone = "Nowhere/City" }, "quota_timezone"},
+		{"short link base url", func(c *Config) { c.ShortLinkBaseURL = "example.com" }, "short_link_base_url"},
+		{"log level", func(c *Config) { c.LogLevel = "loud" }, "log_level"},
+		{"send burst", func(c *Config) { c.SendRateLimit, c.SendRateBurst = 5, 0 }, "send_rate_burst"},
+		{"provider limits", func(c *Config) { c.ProviderRateLimits = "mock=fast" }, "provider_rate_limits"},
+		{"provider and sender limits", func(c *Config) { c.ProviderRateLimits = "mock=100:200,mock/ACME=10" }, ""},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			c := defaults()
+			tc.mutate(c)
+			keys := problemKeys(c)
+			if tc.key == "" {
+				if len(keys) > 0 {
+					t.Fatalf("unexpected problems: %v", keys)
+				}
+				return
+			}
+			if len(keys) != 1 || keys[0] != tc.key {
+				t.Fatalf("problems = %v, want only %s", keys, tc.key)
+			}
+		})
+	}
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	body := "server_port: \"9001\"\nredis_addr: file:6379\nrefund_job_interval: 90\nlog_level: warn\n"
+	if err := os.WriteFile(path, []byte(body), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("CONFIG_FILE", path)
+	t.Setenv("REDIS_ADDR", "env:6379")
+	t.Setenv("LOG_LEVEL", "error")
+
+	cfg, err := Load([]string{"-log-level", "debug"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.ServerPort != "9001" {
+		t.Errorf("server_port = %q, want the file's value", cfg.ServerPort)
+	}
+	if cfg.RedisAddr != "env:6379" {
+		t.Errorf("redis_addr = %q, want the environment's value over the file's", cfg.RedisAddr)
+	}
+	if cfg.LogLevel != "debug" {
+		t.Errorf("log_level = %q, want the flag's value over the environment's", cfg.LogLevel)
+	}
+	if cfg.RefundInterval != 90*time.Second {
+		t.Errorf("refund_job_interval = %s, want a bare integer read as seconds", cfg.RefundInterval)
+	}
+}
+
+func TestLoadReportsEveryProblem(t *testing.T) {
+	t.Setenv("CONFIG_FILE", "")
+	t.Setenv("SERVER_PORT", "none")
+	t.Setenv("RESERVATION_TTL", "soon")
+
+	_, err := Load([]string{"-kafka-initial-offset", "middle"})
+	if err == nil {
+		t.Fatal("invalid configuration was accepted")
+	}
+	for _, want := range []string{"server_port", "RESERVATION_TTL", "kafka_initial_offset"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error does not mention %s:\n%v", want, err)
+		}
+	}
+}
+
+func TestLoadLegacyReservationTTL(t *testing.T) {
+	t.Setenv("CONFIG_FILE", "")
+	t.Setenv("WALLET_RESERVATION_TTL", "45")
+
+	cfg, err := Load(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.ReservationTTL != 45*time.Second {
+		t.Fatalf("reservation_ttl = %s, want WALLET_RESERVATION_TTL read as seconds", cfg.ReservationTTL)
+	}
+
+	t.Setenv("RESERVATION_TTL", "2m")
+	if cfg, err = Load(nil); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.ReservationTTL != 2*time.Minute {
+		t.Fatalf("reservation_ttl = %s, want RESERVATION_TTL over its alias", cfg.ReservationTTL)
+	}
+}
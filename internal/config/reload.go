@@ -0,0 +1,138 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"arvan-sms-gateway/internal/logger"
+	"go.uber.org/zap"
+)
+
+// pollInterval is how often the config file's modification time is checked.
+const pollInterval = 5 * time.Second
+
+var (
+	live       atomic.Pointer[Reloadable]
+	configPath string
+
+	mu      sync.Mutex
+	current *Config
+	args    []string
+)
+
+// MustLoad loads the configuration or exits with every problem listed.
+func MustLoad(cmdArgs []string) *Config {
+	cfg, err := Load(cmdArgs)
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	// reload compares against a private copy; cfg itself is never mutated.
+	startup := *cfg
+	mu.Lock()
+	current, args = &startup, cmdArgs
+	mu.Unlock()
+	r := cfg.Reloadable
+	live.Store(&r)
+	return cfg
+}
+
+// Live returns the current reloadable settings.
+func Live() *Reloadable {
+	if r := live.Load(); r != nil {
+		return r
+	}
+	return &defaults().Reloadable
+}
+
+// Watch applies the log level and re-reads the configuration on SIGHUP and
+// whenever the config file changes. Only Reloadable fields take effect; a
+// changed startup setting is reported and ignored until the next restart.
+func Watch() {
+	if err := logger.SetLevel(Live().LogLevel); err != nil {
+		logger.Error("Invalid log level", zap.Error(err))
+	}
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	ticker := time.NewTicker(pollInterval)
+
+	go func() {
+		modTime := fileModTime()
+		for {
+			select {
+			case <-hup:
+				logger.Info("SIGHUP received, reloading configuration")
+			case <-ticker.C:
+				t := fileModTime()
+				if t.Equal(modTime) {
+					continue
+				}
+				modTime = t
+				logger.Info("Configuration file changed, reloading", zap.String("path", configPath))
+			}
+			reload()
+		}
+	}()
+}
+
+func fileModTime() time.Time {
+	if configPath == "" {
+		return time.Time{}
+	}
+	info, err := os.Stat(configPath)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
+
+func reload() {
+	mu.Lock()
+	defer mu.Unlock()
+
+	next, err := Load(args)
+	if err != nil {
+		logger.Error("Configuration reload rejected, keeping the current settings", zap.Error(err))
+		return
+	}
+
+	var changed, restart []string
+	prev := settings(current)
+	for i, s := range settings(next) {
+		if reflect.DeepEqual(s.value.Interface(), prev[i].value.Interface()) {
+			continue
+		}
+		if s.static {
+			restart = append(restart, s.key)
+		} else {
+			changed = append(changed, s.key)
+		}
+	}
+	if len(restart) > 0 {
+		logger.Warn("Configuration changes that need a restart were ignored", zap.Strings("keys", restart))
+	}
+	if len(changed) == 0 {
+		return
+	}
+
+	r := next.Reloadable
+	live.Store(&r)
+	current.Reloadable = r
+	if err := logger.SetLevel(r.LogLevel); err != nil {
+		logger.Error("Invalid log level", zap.Error(err))
+	}
+	logger.Info("Configuration reloaded", zap.Strings("changed", changed))
+}
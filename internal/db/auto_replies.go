@@ -0,0 +1,178 @@
+package db
+
+import (
+	"arvan-sms-gateway/internal/models"
+	"context"
+	"database/sql"
+	"github.com/lib/pq"
+	"time"
+)
+
+const autoReplyColumns = `id, user_id, number, match_type, keyword, reply, action, tag, cooldown_seconds, created_at`
+
+func scanAutoReplyRule(row interface{ Scan(...any) error }) (models.AutoReplyRule, error) {
+	var r models.AutoReplyRule
+	err := row.Scan(&r.ID, &r.UserID, &r.Number, &r.MatchType, &r.Keyword, &r.Reply, &r.Action, &r.Tag,
+		&r.CooldownSeconds, &r.CreatedAt)
+	return r, err
+}
+
+func CreateAutoReplyRule(ctx context.Context, r *models.AutoReplyRule) error {
+	return DB.QueryRowContext(ctx, `
+        INSERT INTO auto_reply_rules (user_id, number, match_type, keyword, reply, action, tag, cooldown_seconds)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+        RETURNING id, created_at`,
+		r.UserID, r.Number, r.MatchType, r.Keyword, r.Reply, r.Action, r.Tag, r.CooldownSeconds).
+		Scan(&r.ID, &r.CreatedAt)
+}
+
+// ListAutoReplyRules returns the user's rules, only those for number when it
+// is not empty, oldest first.
+func ListAutoReplyRules(ctx context.Context, userID, number string) ([]models.AutoReplyRule, error) {
+	rows, err := DB.QueryContext(ctx, `
+        SELECT `+autoReplyColumns+`
+        FROM auto_reply_rules
+        WHERE user_id = $1 AND ($2 = '' OR number = $2)
+        ORDER BY id`, userID, number)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	rules := []models.AutoReplyRule{}
+	for rows.Next() {
+		r, err := scanAutoReplyRule(rows)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, r)
+	}
+	return rules, rows.Err()
+}
+
+// DeleteAutoReplyRule reports whether the rule existed.
+func DeleteAutoReplyRule(ctx context.Context, id int64) (bool, error) {
+	res, err := DB.ExecContext(ctx, `DELETE FROM auto_reply_rules WHERE id = $1`, id)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+// HasInboundRoute reports whether number is routed to userID by any keyword.
+func HasInboundRoute(ctx context.Context, userID, number string) (bool, error) {
+	var ok bool
+	err := DB.QueryRowContext(ctx, `
+        SELECT EXISTS (SELECT 1 FROM inbound_routes WHERE user_id = $1 AND number = $2)`, userID, number).Scan(&ok)
+	return ok, err
+}
+
+// TakeAutoReplyCooldown records a reply by rule to sender and reports true,
+// or false when the rule replied to sender less than cooldown ago.
+func TakeAutoReplyCooldown(ctx context.Context, ruleID int64, sender string, cooldown time.Duration) (bool, error) {
+	err := DB.QueryRowContext(ctx, `
+        INSERT INTO auto_reply_cooldowns (rule_id, sender, replied_at)
+        VALUES ($1, $2, NOW())
+        ON CONFLICT (rule_id, sender) DO UPDATE SET replied_at = NOW()
+        WHERE auto_reply_cooldowns.replied_at <= NOW() - make_interval(secs => $3)
+        RETURNING rule_id`, ruleID, sender, cooldown.Seconds()).Scan(&ruleID)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// SetInboundReply links an inbound message to the rule that answered it and
+// the reply sent, if any.
+func SetInboundReply(ctx context.Context, inboundID string, ruleID int64, replyMessageID string) error {
+	_, err := DB.ExecContext(ctx, `
+        UPDATE inbound_messages SET auto_reply_rule_id = $2, reply_message_id = NULLIF($3, '')::uuid
+        WHERE id = $1`, inboundID, ruleID, replyMessageID)
+	return err
+}
+
+func AddOptOut(ctx context.Context, userID, phone string, ruleID int64) error {
+	_, err := DB.ExecContext(ctx, `
+        INSERT INTO opt_outs (user_id, phone_number, rule_id) VALUES ($1, $2, $3)
+        ON CONFLICT (user_id, phone_number) DO NOTHING`, userID, phone, ruleID)
+	return err
+}
+
+func RemoveOptOut(ctx context.Context, userID, phone string) error {
+	_, err := DB.ExecContext(ctx, `DELETE FROM opt_outs WHERE user_id = $1 AND phone_number = $2`, userID, phone)
+	return err
+}
+
+// OptedOut returns which of phones opted out of userID's marketing messages.
+// Phones are compared as given; callers pass normalized numbers.
+func OptedOut(ctx context.Context, userID string, phones []string) (map[string]bool, error) {
+	rows, err := DB.QueryContext(ctx, `
+        SELECT phone_number FROM opt_outs
+        WHERE user_id = $1 AND phone_number = ANY($2)`, userID, pq.Array(phones))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := map[string]bool{}
+	for rows.Next() {
+		var phone string
+		if err := rows.Scan(&phone); err != nil {
+			return nil, err
+		}
+		out[phone] = true
+	}
+	return out, rows.Err()
+}
+
+func ListOptOuts(ctx context.Context, userID string) ([]models.OptOut, error) {
+	rows, err := DB.QueryContext(ctx, `
+        SELECT phone_number, rule_id, created_at FROM opt_outs
+        WHERE user_id = $1
+        ORDER BY created_at, phone_number`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	optOuts := []models.OptOut{}
+	for rows.Next() {
+		var o models.OptOut
+		var ruleID sql.NullInt64
+		if err := rows.Scan(&o.PhoneNumber, &ruleID, &o.CreatedAt); err != nil {
+			return nil, err
+		}
+		if ruleID.Valid {
+			o.RuleID = &ruleID.Int64
+		}
+		optOuts = append(optOuts, o)
+	}
+	return optOuts, rows.Err()
+}
+
+func AddContactTag(ctx context.Context, userID, phone, tag string) error {
+	_, err := DB.ExecContext(ctx, `
+        INSERT INTO contact_tags (user_id, phone_number, tag) VALUES ($1, $2, $3)
+        ON CONFLICT DO NOTHING`, userID, phone, tag)
+	return err
+}
+
+// ListContactTags returns the user's tagged numbers, only those with tag when
+// it is not empty.
+func ListContactTags(ctx context.Context, userID, tag string) ([]models.ContactTag, error) {
+	rows, err := DB.QueryContext(ctx, `
+        SELECT phone_number, tag, created_at FROM contact_tags
+        WHERE user_id = $1 AND ($2 = '' OR tag = $2)
+        ORDER BY tag, phone_number`, userID, tag)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	tags := []models.ContactTag{}
+	for rows.Next() {
+		var t models.ContactTag
+		if err := rows.Scan(&t.PhoneNumber, &t.Tag, &t.CreatedAt); err != nil {
+			return nil, err
+		}
+		tags = append(tags, t)
+	}
+	return tags, rows.Err()
+}
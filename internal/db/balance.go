@@ -1,6 +1,40 @@
 package db
 
-import "database/sql"
+import (
+	"context"
+	"database/sql"
+
+	"arvan-sms-gateway/internal/alerts"
+)
+
+const (
+	AccountPrepaid  = "prepaid"
+	AccountPostpaid = "postpaid"
+)
+
+// Wallet is a user's balance and credit limit. A postpaid account may debit
+// its balance down to -CreditLimit; a prepaid account has no credit.
+type Wallet struct {
+	AccountType string
+	Balance     int64
+	CreditLimit int64
+}
+
+// Available is what the user can still spend, and what the Redis wallet
+// counter mirrors.
+func (w Wallet) Available() int64 {
+	return w.Balance + w.CreditLimit
+}
+
+// Prepaid is the credit the user paid for and has not used yet.
+func (w Wallet) Prepaid() int64 {
+	return max(w.Balance, 0)
+}
+
+// Outstanding is the usage taken on credit that is yet to be invoiced.
+func (w Wallet) Outstanding() int64 {
+	return max(-w.Balance, 0)
+}
 
 func GetUserBalance(userID string) (int64, error) {
 	var balance int64
@@ -11,24 +45,58 @@ func GetUserBalance(userID string) (int64, error) {
 	return balance, nil
 }
 
-func DeductBalance(userID string, amount int64) error {
-	tx, err := DB.Begin()
+func GetWallet(ctx context.Context, userID string) (Wallet, error) {
+	var w Wallet
+	err := DB.QueryRowContext(ctx, `SELECT account_type, balance, credit_limit FROM users WHERE id = $1`, userID).
+		Scan(&w.AccountType, &w.Balance, &w.CreditLimit)
+	return w, err
+}
+
+// Debit takes amount from the user's balance if it stays at or above
+// -credit_limit. Every reserver and DeductBalance debits through it, so
+// prepaid and postpaid accounts follow the same rule everywhere. When the
+// debit is refused the current wallet is returned with ok set to false.
+func Debit(ctx context.Context, tx *sql.Tx, userID string, amount int64) (w Wallet, ok bool, err error) {
+	err = tx.QueryRowContext(ctx, `
+        UPDATE users SET balance = balance - $1
+        WHERE id = $2 AND balance - $1 >= -credit_limit
+        RETURNING account_type, balance, credit_limit`, amount, userID).
+		Scan(&w.AccountType, &w.Balance, &w.CreditLimit)
+	if err == sql.ErrNoRows {
+		err = tx.QueryRowContext(ctx, `SELECT account_type, balance, credit_limit FROM users WHERE id = $1`, userID).
+			Scan(&w.AccountType, &w.Balance, &w.CreditLimit)
+		return w, false, err
+	}
+	return w, err == nil, err
+}
+
+func DeductBalance(ctx context.Context, userID string, amount int64) error {
+	tx, err := DB.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback()
 
-	var balance int64
-	if err := tx.QueryRow(`SELECT balance FROM users WHERE id=$1 FOR UPDATE`, userID).Scan(&balance); err != nil {
+	available, err := deductBalance(ctx, tx, userID, amount)
+	if err != nil {
 		return err
 	}
-	if balance < amount {
-		return sql.ErrNoRows
-	}
-
-	if _, err := tx.Exec(`UPDATE users SET balance=balance-$1 WHERE id=$2`, amount, userID); err != nil {
+	if err := tx.Commit(); err != nil {
 		return err
 	}
+	alerts.Evaluate(userID, available)
+	return nil
+}
 
-	return tx.Commit()
+// deductBalance debits amount and returns the available balance left. It
+// returns sql.ErrNoRows when the debit would exceed the credit limit.
+func deductBalance(ctx context.Context, tx *sql.Tx, userID string, amount int64) (int64, error) {
+	w, ok, err := Debit(ctx, tx, userID, amount)
+	if err != nil {
+		return 0, err
+	}
+	if !ok {
+		return 0, sql.ErrNoRows
+	}
+	return w.Available(), nil
 }
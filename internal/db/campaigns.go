@@ -0,0 +1,244 @@
+package db
+
+import (
+	"arvan-sms-gateway/internal/models"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+
+	"github.com/lib/pq"
+)
+
+var ErrCampaignNotDraft = errors.New("recipients can only be added to a draft campaign")
+
+const campaignColumns = `id, user_id, name, body, status, rate_per_second, shorten_links, last_error, created_at,
+        started_at, finished_at`
+
+func scanCampaign(row interface{ Scan(...any) error }) (*models.Campaign, error) {
+	var c models.Campaign
+	var started, finished sql.NullTime
+	err := row.Scan(&c.ID, &c.UserID, &c.Name, &c.Body, &c.Status, &c.RatePerSecond, &c.ShortenLinks, &c.LastError,
+		&c.CreatedAt, &started, &finished)
+	if err != nil {
+		return nil, err
+	}
+	if started.Valid {
+		c.StartedAt = &started.Time
+	}
+	if finished.Valid {
+		c.FinishedAt = &finished.Time
+	}
+	return &c, nil
+}
+
+func CreateCampaign(ctx context.Context, c *models.Campaign) error {
+	return DB.QueryRowContext(ctx, `
+        INSERT INTO campaigns (id, user_id, name, body, rate_per_second, shorten_links)
+        VALUES ($1, $2, $3, $4, $5, $6)
+        RETURNING status, created_at`, c.ID, c.UserID, c.Name, c.Body, c.RatePerSecond, c.ShortenLinks).
+		Scan(&c.Status, &c.CreatedAt)
+}
+
+func GetCampaign(ctx context.Context, id string) (*models.Campaign, error) {
+	return scanCampaign(DB.QueryRowContext(ctx, `SELECT `+campaignColumns+` FROM campaigns WHERE id = $1`, id))
+}
+
+// SetCampaignStatus moves a campaign to status `to` if it is in one of
+// `from`, and reports whether it did.
+func SetCampaignStatus(ctx context.Context, id string, to models.CampaignStatus, from ...models.CampaignStatus) (bool, error) {
+	res, err := DB.ExecContext(ctx, `
+        UPDATE campaigns SET
+            status = $2,
+            last_error = CASE WHEN $2 = 'running' THEN '' ELSE last_error END,
+            started_at = CASE WHEN $2 = 'running' THEN COALESCE(started_at, NOW()) ELSE started_at END,
+            finished_at = CASE WHEN $2 IN ('completed', 'cancelled') THEN NOW() ELSE finished_at END,
+            next_dispatch_at = NOW(),
+            updated_at = NOW()
+        WHERE id = $1 AND status = ANY($3)`, id, to, pq.Array(from))
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+// AddCampaignRecipients stores recipients of a draft campaign in batches and
+// returns how many were new; phone numbers already in the campaign are
+// skipped.
+func AddCampaignRecipients(ctx context.Context, campaignID string, recipients []models.CampaignRecipient) (int, error) {
+	tx, err := DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var status models.CampaignStatus
+	if err := tx.QueryRowContext(ctx, `SELECT status FROM campaigns WHERE id = $1 FOR UPDATE`, campaignID).Scan(&status); err != nil {
+		return 0, err
+	}
+	if status != models.CampaignDraft {
+		return 0, ErrCampaignNotDraft
+	}
+
+	const batch = 1000
+	added := 0
+	for start := 0; start < len(recipients); start += batch {
+		chunk := recipients[start:min(start+batch, len(recipients))]
+		phones := make([]string, len(chunk))
+		vars := make([]string, len(chunk))
+		ids := make([]string, len(chunk))
+		for i, r := range chunk {
+			v, err := json.Marshal(r.Variables)
+			if err != nil {
+				return 0, err
+			}
+			phones[i], vars[i], ids[i] = r.PhoneNumber, string(v), r.MessageID
+		}
+		res, err := tx.ExecContext(ctx, `
+            INSERT INTO campaign_recipients (campaign_id, phone_number, variables, message_id)
+            SELECT $1, r.phone, r.vars, r.message_id
+            FROM unnest($2::text[], $3::jsonb[], $4::uuid[]) AS r(phone, vars, message_id)
+            ON CONFLICT DO NOTHING`, campaignID, pq.Array(phones), pq.Array(vars), pq.Array(ids))
+		if err != nil {
+			return 0, err
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return 0, err
+		}
+		added += int(n)
+	}
+	return added, tx.Commit()
+}
+
+func CountCampaignRecipients(ctx context.Context, campaignID string) (total int64, pending int64, err error) {
+	err = DB.QueryRowContext(ctx, `
+        SELECT COUNT(*), COUNT(*) FILTER (WHERE dispatched_at IS NULL)
+        FROM campaign_recipients WHERE campaign_id = $1`, campaignID).Scan(&total, &pending)
+	return total, pending, err
+}
+
+// GetCampaignProgress counts recipients not dispatched yet and dispatched
+// messages by status.
+func GetCampaignProgress(ctx context.Context, campaignID string) (models.CampaignProgress, error) {
+	p := models.CampaignProgress{Messages: map[string]int64{}}
+	rows, err := DB.QueryContext(ctx, `
+        SELECT r.dispatched_at IS NULL, COALESCE(m.status, 'unknown'), COUNT(*)
+        FROM campaign_recipients r
+        LEFT JOIN messages m ON m.message_id = r.message_id
+        WHERE r.campaign_id = $1
+        GROUP BY 1, 2`, campaignID)
+	if err != nil {
+		return p, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var pending bool
+		var status string
+		var n int64
+		if err := rows.Scan(&pending, &status, &n); err != nil {
+			return p, err
+		}
+		p.Total += n
+		if pending {
+			p.Pending += n
+			continue
+		}
+		p.Messages[status] += n
+	}
+	return p, rows.Err()
+}
+
+// CampaignDispatch sends a chunk of recipients and returns how many of them,
+// from the start, were handled, and a reason when the campaign must pause.
+type CampaignDispatch func(ctx context.Context, c *models.Campaign, recipients []models.CampaignRecipient) (int, string)
+
+// DispatchDueCampaign locks one running campaign whose next dispatch is due
+// and hands up to rate_per_second pending recipients to dispatch. It reports
+// whether a campaign was due. The campaign row is locked with SKIP LOCKED
+// and its next dispatch moved one second ahead, so every replica can run
+// this without exceeding the campaign's rate.
+func DispatchDueCampaign(ctx context.Context, dispatch CampaignDispatch) (bool, error) {
+	tx, err := DB.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	c, err := scanCampaign(tx.QueryRowContext(ctx, `
+        SELECT `+campaignColumns+` FROM campaigns
+        WHERE status = 'running' AND next_dispatch_at <= NOW()
+        ORDER BY next_dispatch_at
+        LIMIT 1
+        FOR UPDATE SKIP LOCKED`))
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+        SELECT phone_number, variables, message_id
+        FROM campaign_recipients
+        WHERE campaign_id = $1 AND dispatched_at IS NULL
+        ORDER BY id
+        LIMIT $2`, c.ID, c.RatePerSecond)
+	if err != nil {
+		return false, err
+	}
+	var recipients []models.CampaignRecipient
+	for rows.Next() {
+		var r models.CampaignRecipient
+		var vars []byte
+		if err := rows.Scan(&r.PhoneNumber, &vars, &r.MessageID); err != nil {
+			rows.Close()
+			return false, err
+		}
+		if err := json.Unmarshal(vars, &r.Variables); err != nil {
+			rows.Close()
+			return false, err
+		}
+		recipients = append(recipients, r)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return false, err
+	}
+
+	if len(recipients) == 0 {
+		_, err := tx.ExecContext(ctx, `
+            UPDATE campaigns SET status = 'completed', finished_at = NOW(), updated_at = NOW()
+            WHERE id = $1`, c.ID)
+		if err != nil {
+			return false, err
+		}
+		return true, tx.Commit()
+	}
+
+	handled, pause := dispatch(ctx, c, recipients)
+	if handled > 0 {
+		ids := make([]string, handled)
+		for i, r := range recipients[:handled] {
+			ids[i] = r.MessageID
+		}
+		_, err := tx.ExecContext(ctx, `
+            UPDATE campaign_recipients SET dispatched_at = NOW()
+            WHERE message_id = ANY($1::uuid[])`, pq.Array(ids))
+		if err != nil {
+			return false, err
+		}
+	}
+	_, err = tx.ExecContext(ctx, `
+        UPDATE campaigns SET
+            status = CASE WHEN $2 = '' THEN status ELSE 'paused' END,
+            last_error = CASE WHEN $2 = '' THEN last_error ELSE $2 END,
+            next_dispatch_at = NOW() + interval '1 second',
+            updated_at = NOW()
+        WHERE id = $1`, c.ID, pause)
+	if err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
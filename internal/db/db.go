@@ -1,9 +1,14 @@
 package db
 
 import (
+	"context"
 	"database/sql"
+	"database/sql/driver"
 
 	"arvan-sms-gateway/internal/logger"
+	"arvan-sms-gateway/internal/tracing"
+	"github.com/XSAM/otelsql"
+	"go.opentelemetry.io/otel/attribute"
 	"go.uber.org/zap"
 
 	_ "github.com/lib/pq"
@@ -13,7 +18,18 @@ var DB *sql.DB
 
 func InitDB(url string) {
 	var err error
-	DB, err = sql.Open("postgres", url)
+	// Statements are traced only when called with a context that carries a
+	// span; background jobs that pass none do not create orphan traces.
+	DB, err = otelsql.Open("postgres", url,
+		otelsql.WithAttributes(attribute.String("db.system", "postgresql")),
+		otelsql.WithSpanOptions(otelsql.SpanOptions{
+			OmitConnResetSession: true,
+			OmitConnPrepare:      true,
+			OmitRows:             true,
+			SpanFilter: func(ctx context.Context, _ otelsql.Method, _ string, _ []driver.NamedValue) bool {
+				return tracing.Active(ctx)
+			},
+		}))
 	if err != nil {
 		logger.Error("Failed to connect to Postgres", zap.Error(err))
 		panic(err)
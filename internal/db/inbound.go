@@ -0,0 +1,204 @@
+package db
+
+import (
+	"arvan-sms-gateway/internal/models"
+	"context"
+	"database/sql"
+	"time"
+)
+
+func CreateInboundRoute(ctx context.Context, r *models.InboundRoute) error {
+	return DB.QueryRowContext(ctx, `
+        INSERT INTO inbound_routes (number, keyword, user_id, webhook_url)
+        VALUES ($1, $2, $3, $4)
+        RETURNING id, created_at`, r.Number, r.Keyword, r.UserID, r.WebhookURL).Scan(&r.ID, &r.CreatedAt)
+}
+
+func ListInboundRoutes(ctx context.Context, userID string) ([]models.InboundRoute, error) {
+	rows, err := DB.QueryContext(ctx, `
+        SELECT id, number, keyword, user_id, webhook_url, created_at
+        FROM inbound_routes WHERE user_id = $1
+        ORDER BY number, keyword`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	routes := []models.InboundRoute{}
+	for rows.Next() {
+		var r models.InboundRoute
+		if err := rows.Scan(&r.ID, &r.Number, &r.Keyword, &r.UserID, &r.WebhookURL, &r.CreatedAt); err != nil {
+			return nil, err
+		}
+		routes = append(routes, r)
+	}
+	return routes, rows.Err()
+}
+
+// DeleteInboundRoute reports whether the route existed.
+func DeleteInboundRoute(ctx context.Context, id int64) (bool, error) {
+	res, err := DB.ExecContext(ctx, `DELETE FROM inbound_routes WHERE id = $1`, id)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+// MatchInboundRoute returns the route of number for keyword, falling back to
+// the number's catch-all route, or sql.ErrNoRows.
+func MatchInboundRoute(ctx context.Context, number, keyword string) (*models.InboundRoute, error) {
+	var r models.InboundRoute
+	err := DB.QueryRowContext(ctx, `
+        SELECT id, number, keyword, user_id, webhook_url, created_at
+        FROM inbound_routes
+        WHERE number = $1 AND keyword IN ('', $2)
+        ORDER BY keyword DESC
+        LIMIT 1`, number, keyword).
+		Scan(&r.ID, &r.Number, &r.Keyword, &r.UserID, &r.WebhookURL, &r.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// InsertInbound stores a received message, routed if route is not nil. It
+// reports false when the provider already reported the message.
+func InsertInbound(ctx context.Context, m *models.InboundMessage, route *models.InboundRoute) (bool, error) {
+	var userID sql.NullString
+	var routeID sql.NullInt64
+	if route != nil {
+		userID = sql.NullString{String: route.UserID, Valid: true}
+		routeID = sql.NullInt64{Int64: route.ID, Valid: true}
+	}
+	err := DB.QueryRowContext(ctx, `
+        INSERT INTO inbound_messages (id, provider, provider_message_id, from_number, to_number, text, keyword,
+                                      user_id, route_id, webhook_status, next_webhook_at, received_at)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10,
+                CASE WHEN $10 = 'pending' THEN NOW() END, $11::timestamptz::timestamp)
+        ON CONFLICT (provider, provider_message_id) DO NOTHING
+        RETURNING seq`,
+		m.ID, m.Provider, m.ProviderMessageID, m.From, m.To, m.Text, m.Keyword,
+		userID, routeID, m.WebhookStatus, m.ReceivedAt).Scan(&m.Seq)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+const inboundColumns = `id, seq, COALESCE(user_id::text, ''), provider, provider_message_id,
+        from_number, to_number, text, keyword, webhook_status, COALESCE(reply_message_id::text, ''), received_at`
+
+func scanInbound(row interface{ Scan(...any) error }) (models.InboundMessage, error) {
+	var m models.InboundMessage
+	err := row.Scan(&m.ID, &m.Seq, &m.UserID, &m.Provider, &m.ProviderMessageID,
+		&m.From, &m.To, &m.Text, &m.Keyword, &m.WebhookStatus, &m.ReplyMessageID, &m.ReceivedAt)
+	return m, err
+}
+
+// ListInbound returns up to limit messages of userID with seq above after,
+// oldest first.
+func ListInbound(ctx context.Context, userID string, after int64, limit int) ([]models.InboundMessage, error) {
+	rows, err := DB.QueryContext(ctx, `
+        SELECT `+inboundColumns+`
+        FROM inbound_messages
+        WHERE user_id = $1 AND seq > $2
+        ORDER BY seq
+        LIMIT $3`, userID, after, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	messages := []models.InboundMessage{}
+	for rows.Next() {
+		m, err := scanInbound(rows)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, m)
+	}
+	return messages, rows.Err()
+}
+
+// InboundDelivery is a routed message waiting for its webhook.
+type InboundDelivery struct {
+	Message    models.InboundMessage
+	WebhookURL string
+	Attempts   int
+}
+
+// DeliverInbound hands up to batch due webhook deliveries to post. A message
+// whose post fails is retried after backoff(attempts) until maxAttempts, then
+// marked failed; one whose route lost its webhook is marked none. Rows are
+// locked with SKIP LOCKED, so every replica can run this.
+func DeliverInbound(ctx context.Context, batch, maxAttempts int, post func(context.Context, InboundDelivery) error,
+	backoff func(attempts int) time.Duration) (delivered int, failed int, err error) {
+	tx, err := DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `
+        SELECT m.id, m.seq, COALESCE(m.user_id::text, ''), m.provider, m.provider_message_id,
+               m.from_number, m.to_number, m.text, m.keyword, m.webhook_status,
+               COALESCE(m.reply_message_id::text, ''), m.received_at,
+               COALESCE(r.webhook_url, ''), m.webhook_attempts
+        FROM inbound_messages m
+        LEFT JOIN inbound_routes r ON r.id = m.route_id
+        WHERE m.webhook_status = 'pending' AND m.next_webhook_at <= NOW()
+        ORDER BY m.next_webhook_at
+        LIMIT $1
+        FOR UPDATE OF m SKIP LOCKED`, batch)
+	if err != nil {
+		return 0, 0, err
+	}
+	var due []InboundDelivery
+	for rows.Next() {
+		var d InboundDelivery
+		m := &d.Message
+		if err := rows.Scan(&m.ID, &m.Seq, &m.UserID, &m.Provider, &m.ProviderMessageID,
+			&m.From, &m.To, &m.Text, &m.Keyword, &m.WebhookStatus, &m.ReplyMessageID, &m.ReceivedAt,
+			&d.WebhookURL, &d.Attempts); err != nil {
+			rows.Close()
+			return 0, 0, err
+		}
+		due = append(due, d)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, 0, err
+	}
+
+	for _, d := range due {
+		if d.WebhookURL == "" {
+			_, err := tx.ExecContext(ctx, `UPDATE inbound_messages SET webhook_status = 'none' WHERE id = $1`, d.Message.ID)
+			if err != nil {
+				return 0, 0, err
+			}
+			continue
+		}
+		postErr := post(ctx, d)
+		if postErr == nil {
+			_, err := tx.ExecContext(ctx, `
+                UPDATE inbound_messages SET webhook_status = 'delivered', webhook_attempts = webhook_attempts + 1
+                WHERE id = $1`, d.Message.ID)
+			if err != nil {
+				return 0, 0, err
+			}
+			delivered++
+			continue
+		}
+		_, err := tx.ExecContext(ctx, `
+            UPDATE inbound_messages SET
+                webhook_attempts = webhook_attempts + 1,
+                webhook_last_error = $2,
+                webhook_status = CASE WHEN webhook_attempts + 1 >= $3 THEN 'failed' ELSE 'pending' END,
+                next_webhook_at = NOW() + make_interval(secs => $4)
+            WHERE id = $1`, d.Message.ID, postErr.Error(), maxAttempts, backoff(d.Attempts+1).Seconds())
+		if err != nil {
+			return 0, 0, err
+		}
+		failed++
+	}
+	return delivered, failed, tx.Commit()
+}
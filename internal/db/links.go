@@ -0,0 +1,92 @@
+package db
+
+import (
+	"arvan-sms-gateway/internal/models"
+	"context"
+	"database/sql"
+)
+
+// ClickShortLink records a click on token and returns the URL to redirect
+// to, or sql.ErrNoRows for an unknown token.
+func ClickShortLink(ctx context.Context, token, ip, userAgent string) (string, error) {
+	var url string
+	err := DB.QueryRowContext(ctx, `
+        WITH link AS (
+            SELECT token, url FROM short_links WHERE token = $1
+        ), click AS (
+            INSERT INTO link_clicks (token, ip, user_agent)
+            SELECT token, $2, $3 FROM link
+        )
+        SELECT url FROM link`, token, ip, userAgent).Scan(&url)
+	return url, err
+}
+
+// GetMessageClicks counts the clicks on each short link of a message.
+func GetMessageClicks(ctx context.Context, messageID string) (*models.MessageClickReport, error) {
+	rows, err := DB.QueryContext(ctx, `
+        SELECT l.token, l.url, COUNT(c.id), MIN(c.clicked_at), MAX(c.clicked_at)
+        FROM short_links l
+        LEFT JOIN link_clicks c ON c.token = l.token
+        WHERE l.message_id = $1
+        GROUP BY l.token, l.url
+        ORDER BY MIN(l.created_at), l.token`, messageID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	report := &models.MessageClickReport{MessageID: messageID, Links: []models.LinkClicks{}}
+	for rows.Next() {
+		var l models.LinkClicks
+		var first, last sql.NullTime
+		if err := rows.Scan(&l.Token, &l.URL, &l.Clicks, &first, &last); err != nil {
+			return nil, err
+		}
+		if first.Valid {
+			l.FirstClickAt, l.LastClickAt = &first.Time, &last.Time
+		}
+		report.TotalClicks += l.Clicks
+		report.Links = append(report.Links, l)
+	}
+	return report, rows.Err()
+}
+
+// GetCampaignClicks summarizes the clicks on a campaign's short links, in
+// total and for its 100 most clicked URLs.
+func GetCampaignClicks(ctx context.Context, campaignID string) (*models.CampaignClickReport, error) {
+	report := &models.CampaignClickReport{CampaignID: campaignID, URLs: []models.URLClicks{}}
+	err := DB.QueryRowContext(ctx, `
+        SELECT COUNT(DISTINCT l.message_id),
+               COUNT(DISTINCT l.message_id) FILTER (WHERE c.id IS NOT NULL),
+               COUNT(c.id)
+        FROM short_links l
+        LEFT JOIN link_clicks c ON c.token = l.token
+        WHERE l.campaign_id = $1`, campaignID).
+		Scan(&report.Messages, &report.MessagesClicked, &report.TotalClicks)
+	if err != nil {
+		return nil, err
+	}
+	if report.Messages > 0 {
+		report.ClickRate = float64(report.MessagesClicked) / float64(report.Messages)
+	}
+
+	rows, err := DB.QueryContext(ctx, `
+        SELECT l.url, COUNT(c.id), COUNT(DISTINCT l.message_id) FILTER (WHERE c.id IS NOT NULL)
+        FROM short_links l
+        LEFT JOIN link_clicks c ON c.token = l.token
+        WHERE l.campaign_id = $1
+        GROUP BY l.url
+        ORDER BY 2 DESC, l.url
+        LIMIT 100`, campaignID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var u models.URLClicks
+		if err := rows.Scan(&u.URL, &u.Clicks, &u.MessagesClicked); err != nil {
+			return nil, err
+		}
+		report.URLs = append(report.URLs, u)
+	}
+	return report, rows.Err()
+}
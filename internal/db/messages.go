@@ -1,26 +1,257 @@
 package db
 
 import (
+	"arvan-sms-gateway/internal/alerts"
 	"arvan-sms-gateway/internal/models"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
 )
 
-func InsertMessage(req models.SMSRequest, status string) error {
-	_, err := DB.Exec(`
-        INSERT INTO messages (message_id, user_id, phone_number, message, cost, status)
-        VALUES ($1, $2, $3, $4, 1, $5)`,
-		req.MessageID, req.UserID, req.PhoneNumber, req.Message, status)
+var ErrInvalidTransition = errors.New("invalid message status transition")
+
+func InsertMessage(ctx context.Context, req models.SMSRequest, status models.MessageStatus) error {
+	tx, err := DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// an OTP is stored masked and flagged as not yet verified
+	text, otpVerified := req.Message, sql.NullBool{}
+	if req.OTPMasked != "" {
+		text, otpVerified = req.OTPMasked, sql.NullBool{Valid: true}
+	}
+	_, err = tx.ExecContext(ctx, `
+        INSERT INTO messages (message_id, user_id, phone_number, message, cost, status, priority, campaign_id, expires_at, otp_verified, sender_id)
+        VALUES ($1, $2, $3, $4, 1, $5, $6, NULLIF($7, '')::uuid, $8::timestamptz::timestamp, $9, NULLIF($10, ''))`,
+		req.MessageID, req.UserID, req.PhoneNumber, text, status, req.Priority, req.CampaignID, req.ExpiresAt, otpVerified, req.SenderID)
+	if err != nil {
+		return err
+	}
+	for _, l := range req.Links {
+		_, err := tx.ExecContext(ctx, `
+            INSERT INTO short_links (token, message_id, campaign_id, url)
+            VALUES ($1, $2, NULLIF($3, '')::uuid, $4)`, l.Token, req.MessageID, req.CampaignID, l.URL)
+		if err != nil {
+			return err
+		}
+	}
+	if err := insertEvent(ctx, tx, req.MessageID, "", status, "accepted"); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// IsDuplicate reports whether err is a unique violation, e.g. InsertMessage
+// for a message id that already exists.
+func IsDuplicate(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
+
+func insertEvent(ctx context.Context, tx *sql.Tx, messageID string, from, to models.MessageStatus, reason string) error {
+	_, err := tx.ExecContext(ctx, `
+        INSERT INTO message_events (message_id, from_status, to_status, reason)
+        VALUES ($1, NULLIF($2, ''), $3, $4)`, messageID, from, to, reason)
 	return err
 }
 
-func UpdateMessageStatus(messageID, status string) {
-	DB.Exec(`UPDATE messages SET status = $1 WHERE message_id = $2`, status, messageID)
+// transition moves a message to the given status if the state machine allows
+// it from the current one, and records the event. The conditional update makes
+// concurrent transitions safe: only one of them can leave a given status.
+func transition(ctx context.Context, tx *sql.Tx, messageID string, to models.MessageStatus, reason string) (models.MessageStatus, error) {
+	var from models.MessageStatus
+	err := tx.QueryRowContext(ctx, `
+        WITH prev AS (SELECT status FROM messages WHERE message_id = $1 FOR UPDATE)
+        UPDATE messages SET status = $2, updated_at = NOW()
+        FROM prev
+        WHERE messages.message_id = $1 AND prev.status = ANY($3)
+        RETURNING prev.status`, messageID, to, pq.Array(models.SourcesOf(to))).Scan(&from)
+	if err == sql.ErrNoRows {
+		var current models.MessageStatus
+		if err := tx.QueryRowContext(ctx, `SELECT status FROM messages WHERE message_id = $1`, messageID).Scan(&current); err != nil {
+			return "", err
+		}
+		return current, fmt.Errorf("%w: %s -> %s", ErrInvalidTransition, current, to)
+	}
+	if err != nil {
+		return "", err
+	}
+	return from, insertEvent(ctx, tx, messageID, from, to, reason)
+}
+
+// TransitionMessage applies a single status transition. It returns
+// ErrInvalidTransition when the message is not in a status that may move to
+// the requested one, and sql.ErrNoRows when the message does not exist.
+func TransitionMessage(ctx context.Context, messageID string, to models.MessageStatus, reason string) error {
+	tx, err := DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := transition(ctx, tx, messageID, to, reason); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
-func GetMessageStatus(messageID string) (string, error) {
+func GetMessageStatus(ctx context.Context, messageID string) (string, error) {
 	var status string
-	err := DB.QueryRow(`SELECT status FROM messages WHERE message_id = $1`, messageID).Scan(&status)
+	err := DB.QueryRowContext(ctx, `SELECT status FROM messages WHERE message_id = $1`, messageID).Scan(&status)
 	if err != nil {
 		return "", err
 	}
 	return status, nil
 }
+
+func GetMessageEvents(ctx context.Context, messageID string) ([]models.MessageEvent, error) {
+	rows, err := DB.QueryContext(ctx, `
+        SELECT COALESCE(from_status, ''), to_status, reason, created_at
+        FROM message_events
+        WHERE message_id = $1
+        ORDER BY id`, messageID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []models.MessageEvent{}
+	for rows.Next() {
+		var e models.MessageEvent
+		if err := rows.Scan(&e.From, &e.To, &e.Reason, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+// ClaimMessage moves a message from queued to sending. Only one worker can win
+// the claim, so a redelivered Kafka record is not dispatched twice. A claim
+// held longer than lease without a provider message id is taken over.
+func ClaimMessage(ctx context.Context, messageID string, lease time.Duration) (bool, error) {
+	tx, err := DB.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	// A claim older than the lease that never reached the provider belongs to
+	// an attempt that died before sending; it goes back to queued and is
+	// claimed again below.
+	var stale bool
+	err = tx.QueryRowContext(ctx, `
+        SELECT status = 'sending' AND provider_message_id IS NULL
+               AND COALESCE(claimed_at, updated_at) < NOW() - make_interval(secs => $2)
+        FROM messages WHERE message_id = $1
+        FOR UPDATE`, messageID, lease.Seconds()).Scan(&stale)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if stale {
+		if _, err := transition(ctx, tx, messageID, models.StatusQueued, "claim lease expired"); err != nil {
+			return false, err
+		}
+	}
+
+	if _, err := transition(ctx, tx, messageID, models.StatusSending, "claimed by worker"); err != nil {
+		if errors.Is(err, ErrInvalidTransition) {
+			return false, nil
+		}
+		return false, err
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE messages SET claimed_at = NOW() WHERE message_id = $1`, messageID); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// GetMessageDispatch returns the status and the provider message id (empty if
+// the provider never accepted the message).
+func GetMessageDispatch(ctx context.Context, messageID string) (models.MessageStatus, string, error) {
+	var status models.MessageStatus
+	var providerID sql.NullString
+	err := DB.QueryRowContext(ctx, `SELECT status, provider_message_id FROM messages WHERE message_id = $1`, messageID).
+		Scan(&status, &providerID)
+	if err != nil {
+		return "", "", err
+	}
+	return status, providerID.String, nil
+}
+
+// SetProviderMessageID records the provider's id of a message being sent. It
+// returns sql.ErrNoRows when the message is not sending.
+func SetProviderMessageID(ctx context.Context, messageID, providerID string) error {
+	res, err := DB.ExecContext(ctx, `UPDATE messages SET provider_message_id = $1 WHERE message_id = $2 AND status = 'sending'`,
+		providerID, messageID)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// CompleteMessage marks a claimed message as sent and, when charge is set,
+// deducts the cost in the same transaction. It returns false when the message
+// was already completed, so the balance is charged at most once per message.
+// The provider already accepted the message, so a refused debit does not undo
+// it: the message is still sent, the charge is written to the ledger as
+// unpaid and unpaid is returned true.
+func CompleteMessage(ctx context.Context, messageID, userID string, cost int64, charge bool) (completed, unpaid bool, err error) {
+	tx, err := DB.BeginTx(ctx, nil)
+	if err != nil {
+		return false, false, err
+	}
+	defer tx.Rollback()
+
+	if _, err := transition(ctx, tx, messageID, models.StatusSent, "accepted by provider"); err != nil {
+		if errors.Is(err, ErrInvalidTransition) {
+			return false, false, nil
+		}
+		return false, false, err
+	}
+
+	if !charge {
+		return true, false, tx.Commit()
+	}
+	available, err := deductBalance(ctx, tx, userID, cost)
+	if err == sql.ErrNoRows {
+		_, err = tx.ExecContext(ctx, `
+            INSERT INTO balance_ledger (user_id, amount, kind, message_id, reason)
+            VALUES ($1, $2, 'unpaid', $3, 'debit refused: insufficient balance')`, userID, cost, messageID)
+		if err != nil {
+			return false, false, err
+		}
+		return true, true, tx.Commit()
+	}
+	if err != nil {
+		return false, false, err
+	}
+	if err := tx.Commit(); err != nil {
+		return false, false, err
+	}
+	alerts.Evaluate(userID, available)
+	return true, false, nil
+}
+
+func MarkOTPVerified(ctx context.Context, messageID string) error {
+	_, err := DB.ExecContext(ctx, `
+        UPDATE messages SET otp_verified = TRUE, otp_verified_at = NOW()
+        WHERE message_id = $1`, messageID)
+	return err
+}
@@ -0,0 +1,175 @@
+package db
+
+import (
+	"arvan-sms-gateway/internal/models"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// CauseQuietHours marks records of deferred messages.
+const CauseQuietHours = "quiet_hours"
+
+// ParkedMessage is a Kafka record kept in Postgres until it can be
+// republished.
+type ParkedMessage struct {
+	MessageID string
+	Topic     string
+	Key       string
+	Payload   string
+	Headers   map[string]string
+	Cause     string
+	Attempts  int
+}
+
+// ParkMessage stores a record for a republish after delay. The message keeps
+// its status; parking it again replaces the stored record.
+func ParkMessage(ctx context.Context, p ParkedMessage, cause, reason string, delay time.Duration) error {
+	_, err := parkMessage(ctx, DB, p, cause, reason, delay)
+	return err
+}
+
+func parkMessage(ctx context.Context, ex interface {
+	ExecContext(context.Context, string, ...any) (sql.Result, error)
+}, p ParkedMessage, cause, reason string, delay time.Duration) (sql.Result, error) {
+	headers, err := json.Marshal(p.Headers)
+	if err != nil {
+		return nil, err
+	}
+	return ex.ExecContext(ctx, `
+        INSERT INTO parked_messages (message_id, topic, msg_key, payload, headers, cause, last_error, next_attempt_at)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, NOW() + make_interval(secs => $8))
+        ON CONFLICT (message_id) DO UPDATE SET
+            topic = EXCLUDED.topic, msg_key = EXCLUDED.msg_key, payload = EXCLUDED.payload,
+            headers = EXCLUDED.headers, cause = EXCLUDED.cause, last_error = EXCLUDED.last_error,
+            next_attempt_at = EXCLUDED.next_attempt_at`,
+		p.MessageID, p.Topic, p.Key, p.Payload, headers, cause, reason, delay.Seconds())
+}
+
+// DeferMessage moves a queued message to deferred and parks its record until
+// delay has passed. It returns ErrInvalidTransition when the message is no
+// longer queued.
+func DeferMessage(ctx context.Context, p ParkedMessage, reason string, delay time.Duration) error {
+	tx, err := DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := transition(ctx, tx, p.MessageID, models.StatusDeferred, reason); err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `
+        UPDATE messages SET deferred_until = NOW() + make_interval(secs => $2)
+        WHERE message_id = $1`, p.MessageID, delay.Seconds())
+	if err != nil {
+		return err
+	}
+	if _, err := parkMessage(ctx, tx, p, CauseQuietHours, reason, delay); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetDeferredUntil returns when a deferred message is due to be queued again.
+func GetDeferredUntil(ctx context.Context, messageID string) (time.Time, error) {
+	var until time.Time
+	err := DB.QueryRowContext(ctx, `SELECT deferred_until FROM messages WHERE message_id = $1`, messageID).Scan(&until)
+	return until, err
+}
+
+// resumeDeferred queues a deferred message again before its record is
+// republished. It reports false when the message left deferred some other
+// way and must not be sent; a message already queued by an earlier attempt
+// is sent.
+func resumeDeferred(ctx context.Context, messageID string) (bool, error) {
+	tx, err := DB.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	current, err := transition(ctx, tx, messageID, models.StatusQueued, "quiet hours over")
+	if errors.Is(err, ErrInvalidTransition) || err == sql.ErrNoRows {
+		return current == models.StatusQueued, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// RepublishParked hands up to batch due records to send. Deferred messages
+// are queued again first. A record that was sent is removed; on the first failure the record is rescheduled after
+// backoff(attempts) and the batch stops, as the rest would fail the same way.
+// Rows are locked with SKIP LOCKED, so every replica can run this.
+func RepublishParked(ctx context.Context, batch int, send func(context.Context, ParkedMessage) error,
+	backoff func(attempts int) time.Duration) (sent int, failed int, err error) {
+	tx, err := DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `
+        SELECT message_id, topic, msg_key, payload, headers, cause, attempts
+        FROM parked_messages
+        WHERE next_attempt_at <= NOW()
+        ORDER BY next_attempt_at
+        LIMIT $1
+        FOR UPDATE SKIP LOCKED`, batch)
+	if err != nil {
+		return 0, 0, err
+	}
+	var due []ParkedMessage
+	for rows.Next() {
+		var p ParkedMessage
+		var headers []byte
+		if err := rows.Scan(&p.MessageID, &p.Topic, &p.Key, &p.Payload, &headers, &p.Cause, &p.Attempts); err != nil {
+			rows.Close()
+			return 0, 0, err
+		}
+		if err := json.Unmarshal(headers, &p.Headers); err != nil {
+			rows.Close()
+			return 0, 0, err
+		}
+		due = append(due, p)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, 0, err
+	}
+
+	for _, p := range due {
+		if p.Cause == CauseQuietHours {
+			ok, err := resumeDeferred(ctx, p.MessageID)
+			if err != nil {
+				return 0, 0, err
+			}
+			if !ok {
+				if _, err := tx.ExecContext(ctx, `DELETE FROM parked_messages WHERE message_id = $1`, p.MessageID); err != nil {
+					return 0, 0, err
+				}
+				continue
+			}
+		}
+		if sendErr := send(ctx, p); sendErr != nil {
+			_, err = tx.ExecContext(ctx, `
+                UPDATE parked_messages
+                SET attempts = attempts + 1, last_error = $2, next_attempt_at = NOW() + make_interval(secs => $3)
+                WHERE message_id = $1`, p.MessageID, sendErr.Error(), backoff(p.Attempts+1).Seconds())
+			if err != nil {
+				return 0, 0, err
+			}
+			failed++
+			break
+		}
+		if _, err := tx.ExecContext(ctx, `DELETE FROM parked_messages WHERE message_id = $1`, p.MessageID); err != nil {
+			return 0, 0, err
+		}
+		sent++
+	}
+	return sent, failed, tx.Commit()
+}
@@ -0,0 +1,45 @@
+package db
+
+import (
+	"arvan-sms-gateway/internal/models"
+	"context"
+)
+
+func ListQuotas(ctx context.Context, userID string) ([]models.Quota, error) {
+	rows, err := DB.QueryContext(ctx, `
+        SELECT user_id, priority, period, max_messages FROM quotas
+        WHERE user_id = $1
+        ORDER BY period, priority`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	quotas := []models.Quota{}
+	for rows.Next() {
+		var q models.Quota
+		if err := rows.Scan(&q.UserID, &q.Priority, &q.Period, &q.Limit); err != nil {
+			return nil, err
+		}
+		quotas = append(quotas, q)
+	}
+	return quotas, rows.Err()
+}
+
+func SetQuota(ctx context.Context, q models.Quota) error {
+	_, err := DB.ExecContext(ctx, `
+        INSERT INTO quotas (user_id, priority, period, max_messages) VALUES ($1, $2, $3, $4)
+        ON CONFLICT (user_id, priority, period) DO UPDATE SET max_messages = $4, updated_at = NOW()`,
+		q.UserID, q.Priority, q.Period, q.Limit)
+	return err
+}
+
+// DeleteQuota reports whether the quota existed.
+func DeleteQuota(ctx context.Context, userID string, priority models.Priority, period models.QuotaPeriod) (bool, error) {
+	res, err := DB.ExecContext(ctx, `
+        DELETE FROM quotas WHERE user_id = $1 AND priority = $2 AND period = $3`, userID, priority, period)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
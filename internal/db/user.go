@@ -1,15 +1,22 @@
 package db
 
+import (
+	"context"
+
+	"github.com/lib/pq"
+)
+
 type User struct {
-	ID      string
-	Balance int64
-	IsVIP   bool
+	ID         string
+	Balance    int64
+	IsVIP      bool
+	Priorities []string // classes the user may send with
 }
 
-func GetUser(userID string) (*User, error) {
+func GetUser(ctx context.Context, userID string) (*User, error) {
 	var u User
-	err := DB.QueryRow(`SELECT id, balance, is_vip FROM users WHERE id=$1`, userID).
-		Scan(&u.ID, &u.Balance, &u.IsVIP)
+	err := DB.QueryRowContext(ctx, `SELECT id, balance, is_vip, allowed_priorities FROM users WHERE id=$1`, userID).
+		Scan(&u.ID, &u.Balance, &u.IsVIP, pq.Array(&u.Priorities))
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,35 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"arvan-sms-gateway/internal/db"
+	"arvan-sms-gateway/internal/logger"
+	"go.uber.org/zap"
+)
+
+// StartCampaignDispatcher hands each running campaign one second's worth of
+// recipients per second. Replicas share the work: a campaign is locked while
+// one of them dispatches it.
+func StartCampaignDispatcher(dispatch db.CampaignDispatch) {
+	ticker := time.NewTicker(time.Second)
+	go func() {
+		for range ticker.C {
+			dispatchCampaigns(dispatch)
+		}
+	}()
+}
+
+func dispatchCampaigns(dispatch db.CampaignDispatch) {
+	for {
+		due, err := db.DispatchDueCampaign(context.Background(), dispatch)
+		if err != nil {
+			logger.Error("campaign dispatch", zap.Error(err))
+			return
+		}
+		if !due {
+			return
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package jobs
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"arvan-sms-gateway/internal/db"
+	"arvan-sms-gateway/internal/logger"
+	"arvan-sms-gateway/internal/metrics"
+	"arvan-sms-gateway/internal/webhook"
+	"go.uber.org/zap"
+)
+
+const (
+	inboundBatchSize  = 20
+	maxInboundBackoff = time.Hour
+)
+
+var webhookClient = webhook.NewClient(5 * time.Second)
+
+// StartInboundWebhooks posts routed inbound messages to their route's
+// webhook, retrying with backoff up to maxAttempts times. It is safe to run
+// on every replica: rows are claimed with SKIP LOCKED.
+func StartInboundWebhooks(interval time.Duration, maxAttempts int) {
+	ticker := time.NewTicker(interval)
+	go func() {
+		for range ticker.C {
+			deliverInbound(interval, maxAttempts)
+		}
+	}()
+}
+
+func deliverInbound(interval time.Duration, maxAttempts int) {
+	post := func(ctx context.Context, d db.InboundDelivery) error {
+		body, _ := json.Marshal(map[string]any{"type": "sms.inbound", "data": d.Message})
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.WebhookURL, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := webhookClient.Do(req)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= 300 {
+			return fmt.Errorf("webhook returned %s", resp.Status)
+		}
+		return nil
+	}
+	backoff := func(attempts int) time.Duration {
+		return min(interval<<min(attempts, 10), maxInboundBackoff)
+	}
+
+	delivered, failed, err := db.DeliverInbound(context.Background(), inboundBatchSize, maxAttempts, post, backoff)
+	metrics.InboundWebhooks.WithLabelValues("delivered").Add(float64(delivered))
+	metrics.InboundWebhooks.WithLabelValues("failed").Add(float64(failed))
+	if err != nil {
+		logger.Error("inbound webhook batch", zap.Error(err))
+		return
+	}
+	if failed > 0 {
+		logger.Warn("inbound webhooks failed", zap.Int("delivered", delivered), zap.Int("failed", failed))
+	}
+}
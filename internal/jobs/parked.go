@@ -0,0 +1,64 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"arvan-sms-gateway/internal/breaker"
+	"arvan-sms-gateway/internal/db"
+	"arvan-sms-gateway/internal/logger"
+	"arvan-sms-gateway/internal/metrics"
+	"arvan-sms-gateway/internal/queue"
+	"go.uber.org/zap"
+)
+
+const (
+	parkedBatchSize  = 100
+	maxParkedBackoff = 5 * time.Minute
+)
+
+// StartParkedRetry republishes messages parked while Kafka or a provider was
+// unavailable. It is safe to run on every replica: rows are claimed with
+// SKIP LOCKED.
+func StartParkedRetry(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	go func() {
+		for range ticker.C {
+			republishParked(interval)
+		}
+	}()
+}
+
+func republishParked(interval time.Duration) {
+	if time.Now().Before(breaker.Kafka().NextTrial()) {
+		return
+	}
+
+	send := func(ctx context.Context, p db.ParkedMessage) error {
+		return queue.SendMessage(queue.WithHeaders(ctx, p.Headers), p.Topic, p.Key, p.Payload)
+	}
+	backoff := func(attempts int) time.Duration {
+		return min(interval<<min(attempts, 10), maxParkedBackoff)
+	}
+	// Keep going while batches come back full, so a backlog such as the
+	// messages deferred overnight drains in one run.
+	var sent, failed int
+	for {
+		n, f, err := db.RepublishParked(context.Background(), parkedBatchSize, send, backoff)
+		sent, failed = sent+n, failed+f
+		metrics.ParkedRepublished.WithLabelValues("sent").Add(float64(n))
+		metrics.ParkedRepublished.WithLabelValues("failed").Add(float64(f))
+		if err != nil {
+			logger.Error("parked message batch", zap.Error(err))
+			return
+		}
+		if n < parkedBatchSize || f > 0 {
+			break
+		}
+	}
+	if sent+failed > 0 {
+		logger.Info("parked messages republished",
+			zap.Int("sent", sent),
+			zap.Int("failed", failed))
+	}
+}
@@ -1,64 +1,53 @@
 package jobs
 
 import (
-	"database/sql"
+	"context"
 	"time"
 
 	"arvan-sms-gateway/internal/logger"
+	"arvan-sms-gateway/internal/metrics"
+	"arvan-sms-gateway/internal/reservation"
 	"go.uber.org/zap"
 )
 
-func StartRefundJob(db *sql.DB, interval time.Duration, batchSize int) {
+// maxRefundBatchesPerTick bounds the work of one tick so a large backlog is
+// drained over several ticks instead of one long-running loop.
+const maxRefundBatchesPerTick = 20
+
+// StartRefundJob settles expired reservations in batches of batchSize. It is
+// safe to run on every replica: rows are claimed with SKIP LOCKED.
+func StartRefundJob(svc *reservation.Service, interval time.Duration, batchSize int) {
 	ticker := time.NewTicker(interval)
 	go func() {
 		for range ticker.C {
-			refundExpired(db, batchSize)
+			refundExpired(svc, batchSize)
 		}
 	}()
 }
 
-func refundExpired(db *sql.DB, batchSize int) {
-	tx, err := db.Begin()
-	if err != nil {
-		logger.Error("refund tx start", zap.Error(err))
-		return
-	}
-	defer tx.Rollback()
-
-	rows, err := tx.Query(`
-        SELECT id, user_id, amount FROM reservations
-        WHERE used=false AND expires_at < NOW()
-        LIMIT $1 FOR UPDATE SKIP LOCKED`, batchSize)
-	if err != nil {
-		logger.Error("refund select", zap.Error(err))
-		return
-	}
-	defer rows.Close()
-
-	var ids []string
-	for rows.Next() {
-		var id string
-		var uid string
-		var amt int64
-		if err := rows.Scan(&id, &uid, &amt); err != nil {
-			logger.Error("refund scan", zap.Error(err))
-			return
-		}
-		_, err := tx.Exec(`UPDATE wallets SET balance=balance+$1 WHERE user_id=$2`, amt, uid)
+func refundExpired(svc *reservation.Service, batchSize int) {
+	var total reservation.SettleResult
+	for i := 0; i < maxRefundBatchesPerTick; i++ {
+		res, err := svc.SettleExpired(context.Background(), batchSize)
+		metrics.RefundProcessed.WithLabelValues("refunded").Add(float64(res.Refunded))
+		metrics.RefundProcessed.WithLabelValues("used").Add(float64(res.Used))
+		metrics.RefundFailed.Add(float64(res.Failed))
+		total.Refunded += res.Refunded
+		total.Used += res.Used
+		total.Failed += res.Failed
 		if err != nil {
-			logger.Error("refund balance", zap.Error(err))
-			return
+			logger.Error("refund batch", zap.Error(err))
+			break
+		}
+		if res.Processed()+res.Failed < batchSize {
+			break
 		}
-		ids = append(ids, id)
-	}
-
-	for _, id := range ids {
-		_, _ = tx.Exec(`DELETE FROM reservations WHERE id=$1`, id)
 	}
 
-	if err := tx.Commit(); err != nil {
-		logger.Error("refund commit", zap.Error(err))
-	} else {
-		logger.Info("refund job done", zap.Int("count", len(ids)))
+	if total.Processed()+total.Failed > 0 {
+		logger.Info("refund job done",
+			zap.Int("refunded", total.Refunded),
+			zap.Int("used", total.Used),
+			zap.Int("failed", total.Failed))
 	}
 }
@@ -0,0 +1,46 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"arvan-sms-gateway/internal/logger"
+	"arvan-sms-gateway/internal/reservation"
+	"go.uber.org/zap"
+)
+
+const (
+	reconcileScanBatch = 500
+	reconcileGrace     = 2 * time.Second
+)
+
+// StartWalletReconciler periodically compares the Redis wallet counters with
+// Postgres. Every gateway replica may run it; an advisory lock lets only one
+// of them work per tick.
+func StartWalletReconciler(svc *reservation.Service, interval time.Duration, dryRun bool) {
+	ticker := time.NewTicker(interval)
+	go func() {
+		for range ticker.C {
+			RunWalletReconcile(context.Background(), svc, dryRun)
+		}
+	}()
+}
+
+func RunWalletReconcile(ctx context.Context, svc *reservation.Service, dryRun bool) (*reservation.ReconcileReport, error) {
+	report, err := svc.Reconcile(ctx, dryRun, reconcileScanBatch, reconcileGrace)
+	if err != nil {
+		logger.Error("wallet reconcile", zap.Error(err))
+		return report, err
+	}
+	if report.Skipped {
+		return report, nil
+	}
+	logger.Info("wallet reconcile done",
+		zap.Bool("dry_run", dryRun),
+		zap.Int("checked", report.Checked),
+		zap.Int("drifted", len(report.Drifted)),
+		zap.Int("corrected", report.Corrected),
+		zap.Int("negative", len(report.Negative)),
+		zap.Int("orphaned", len(report.Orphaned)))
+	return report, nil
+}
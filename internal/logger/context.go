@@ -0,0 +1,77 @@
+package logger
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+type ctxKey struct{}
+
+// ids are the correlation fields carried through a request and, via Kafka
+// headers, into the worker that handles its message.
+type ids struct {
+	requestID string
+	userID    string
+	messageID string
+}
+
+func idsFrom(ctx context.Context) ids {
+	v, _ := ctx.Value(ctxKey{}).(ids)
+	return v
+}
+
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	v := idsFrom(ctx)
+	v.requestID = requestID
+	return context.WithValue(ctx, ctxKey{}, v)
+}
+
+func WithUserID(ctx context.Context, userID string) context.Context {
+	v := idsFrom(ctx)
+	v.userID = userID
+	return context.WithValue(ctx, ctxKey{}, v)
+}
+
+func WithMessageID(ctx context.Context, messageID string) context.Context {
+	v := idsFrom(ctx)
+	v.messageID = messageID
+	return context.WithValue(ctx, ctxKey{}, v)
+}
+
+func RequestID(ctx context.Context) string {
+	return idsFrom(ctx).requestID
+}
+
+// contextFields returns the correlation fields set on ctx, plus the trace ID
+// when ctx carries a sampled span.
+func contextFields(ctx context.Context, fields []zap.Field) []zap.Field {
+	v := idsFrom(ctx)
+	out := make([]zap.Field, 0, len(fields)+4)
+	if v.requestID != "" {
+		out = append(out, zap.String("request_id", v.requestID))
+	}
+	if v.userID != "" {
+		out = append(out, zap.String("user_id", v.userID))
+	}
+	if v.messageID != "" {
+		out = append(out, zap.String("message_id", v.messageID))
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.IsSampled() {
+		out = append(out, zap.String("trace_id", sc.TraceID().String()))
+	}
+	return append(out, fields...)
+}
+
+func InfoCtx(ctx context.Context, msg string, fields ...zap.Field) {
+	Info(msg, contextFields(ctx, fields)...)
+}
+
+func WarnCtx(ctx context.Context, msg string, fields ...zap.Field) {
+	Warn(msg, contextFields(ctx, fields)...)
+}
+
+func ErrorCtx(ctx context.Context, msg string, fields ...zap.Field) {
+	Error(msg, contextFields(ctx, fields)...)
+}
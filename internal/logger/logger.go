@@ -4,23 +4,37 @@ import (
 	"sync"
 
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 )
 
 var (
-	log  *zap.Logger
-	once sync.Once
+	log   *zap.Logger
+	once  sync.Once
+	level = zap.NewAtomicLevelAt(zap.InfoLevel)
 )
 
 func InitLogger() {
 	once.Do(func() {
+		cfg := zap.NewProductionConfig() // یا zap.NewDevelopmentConfig()
+		cfg.Level = level
 		var err error
-		log, err = zap.NewProduction() // یا zap.NewDevelopment()
+		log, err = cfg.Build()
 		if err != nil {
 			panic(err)
 		}
 	})
 }
 
+// SetLevel changes the minimum level of the running logger.
+func SetLevel(name string) error {
+	l, err := zapcore.ParseLevel(name)
+	if err != nil {
+		return err
+	}
+	level.SetLevel(l)
+	return nil
+}
+
 func Info(msg string, fields ...zap.Field) {
 	if log == nil {
 		InitLogger()
@@ -1,6 +1,13 @@
 package metrics
 
-import "github.com/prometheus/client_golang/prometheus"
+import (
+	"net/http"
+
+	"arvan-sms-gateway/internal/logger"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.uber.org/zap"
+)
 
 var (
 	TotalSMSRequests = prometheus.NewCounter(
@@ -10,11 +17,46 @@ var (
 		},
 	)
 
-	QueueLength = prometheus.NewGauge(
+	RequestDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "gateway_request_duration_seconds",
+			Help:    "Gateway request latency by endpoint and stage (total, db_insert, reserve, produce)",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{"endpoint", "stage"},
+	)
+
+	WorkerSendDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "worker_send_duration_seconds",
+			Help:    "Time the provider took to accept or reject a message, by topic and provider",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{"topic", "provider"},
+	)
+
+	WorkerSendOutcomes = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "worker_send_total",
+			Help: "Messages handed to a provider by topic, provider and outcome (sent, failed)",
+		},
+		[]string{"topic", "provider", "outcome"},
+	)
+
+	ReservationPath = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "reservation_path_total",
+			Help: "Reservations by path: fast when the Redis counter agreed with Postgres, slow when it had to be resynced, fallback when Redis was unavailable, chunk for campaign chunks",
+		},
+		[]string{"path"},
+	)
+
+	ConsumerLag = prometheus.NewGaugeVec(
 		prometheus.GaugeOpts{
-			Name: "sms_queue_length",
-			Help: "Current length of the SMS queue",
+			Name: "kafka_consumer_lag",
+			Help: "Records behind the partition high watermark, as seen by the worker consuming it",
 		},
+		[]string{"group", "topic", "partition"},
 	)
 
 	KafkaMessages = prometheus.NewCounter(
@@ -30,11 +72,211 @@ var (
 			Help: "Total number of Kafka message send errors",
 		},
 	)
+
+	WalletDriftUsers = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "wallet_drift_users",
+			Help: "Users whose Redis wallet counter disagreed with Postgres in the last reconciliation",
+		},
+	)
+
+	WalletDriftTokens = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "wallet_drift_tokens",
+			Help: "Sum of absolute Redis/Postgres wallet differences found in the last reconciliation",
+		},
+	)
+
+	WalletNegativeBalances = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "wallet_negative_balance_users",
+			Help: "Users whose Postgres balance is past their credit limit in the last reconciliation",
+		},
+	)
+
+	WalletReconcileCorrections = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "wallet_reconcile_corrections_total",
+			Help: "Total number of Redis wallet counters corrected from Postgres",
+		},
+	)
+
+	RefundProcessed = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "reservation_expired_settled_total",
+			Help: "Expired reservations settled by the refund job, by outcome (refunded, used)",
+		},
+		[]string{"outcome"},
+	)
+
+	RefundFailed = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "reservation_expired_failed_total",
+			Help: "Expired reservations the refund job failed to settle",
+		},
+	)
+
+	BalanceAlerts = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "balance_alert_notifications_total",
+			Help: "Low-balance notifications by channel (sms, webhook) and outcome (sent, failed)",
+		},
+		[]string{"channel", "outcome"},
+	)
+
+	CircuitBreakerState = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "circuit_breaker_state",
+			Help: "Circuit breaker state per dependency: 0 closed, 1 open, 2 half-open",
+		},
+		[]string{"breaker"},
+	)
+
+	CircuitBreakerRejected = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "circuit_breaker_rejected_total",
+			Help: "Calls refused because the dependency's circuit breaker was open",
+		},
+		[]string{"breaker"},
+	)
+
+	ParkedMessages = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "parked_messages_total",
+			Help: "Messages parked for a later retry, by cause (kafka, provider, quiet_hours)",
+		},
+		[]string{"cause"},
+	)
+
+	ParkedRepublished = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "parked_messages_republished_total",
+			Help: "Parked messages handed back to Kafka, by outcome (sent, failed)",
+		},
+		[]string{"outcome"},
+	)
+
+	OTPEvents = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "otp_events_total",
+			Help: "OTP codes by event (issued, cooldown, verified, mismatch, not_found, locked)",
+		},
+		[]string{"event"},
+	)
+
+	MessagesExpired = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "messages_expired_total",
+			Help: "Messages dropped by the worker because their validity elapsed, by priority class",
+		},
+		[]string{"priority"},
+	)
+
+	InboundMessages = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "inbound_messages_total",
+			Help: "Mobile-originated messages received, by outcome (routed, unrouted, duplicate)",
+		},
+		[]string{"outcome"},
+	)
+
+	InboundWebhooks = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "inbound_webhooks_total",
+			Help: "Inbound message webhook posts, by outcome (delivered, failed)",
+		},
+		[]string{"outcome"},
+	)
+
+	QuotaRejections = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "quota_rejections_total",
+			Help: "Messages rejected because a send quota was used up, by quota period (day, month)",
+		},
+		[]string{"period"},
+	)
+
+	LinkClicks = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "link_clicks_total",
+			Help: "Short link clicks redirected",
+		},
+	)
+
+	UnpaidCharges = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "unpaid_charges_total",
+			Help: "Sent messages whose debit was refused and recorded as unpaid in the balance ledger",
+		},
+	)
+
+	ProviderThrottleWait = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "provider_throttle_wait_seconds",
+			Help:    "Time workers waited for a provider send slot, by provider",
+			Buckets: []float64{0, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5},
+		},
+		[]string{"provider"},
+	)
+
+	AutoReplies = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "auto_replies_total",
+			Help: "Inbound messages answered by an auto-reply rule, by outcome (sent, failed, cooldown, action_only)",
+		},
+		[]string{"outcome"},
+	)
+
+	CampaignMessages = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "campaign_messages_total",
+			Help: "Campaign recipients handled by the dispatcher, by outcome (queued, duplicate, opted_out, failed)",
+		},
+		[]string{"outcome"},
+	)
 )
 
 func InitMetrics() {
 	prometheus.MustRegister(TotalSMSRequests)
-	prometheus.MustRegister(QueueLength)
+	prometheus.MustRegister(RequestDuration)
+	prometheus.MustRegister(WorkerSendDuration)
+	prometheus.MustRegister(WorkerSendOutcomes)
+	prometheus.MustRegister(ReservationPath)
+	prometheus.MustRegister(ConsumerLag)
 	prometheus.MustRegister(KafkaMessages)
 	prometheus.MustRegister(KafkaErrors)
+	prometheus.MustRegister(WalletDriftUsers)
+	prometheus.MustRegister(WalletDriftTokens)
+	prometheus.MustRegister(WalletNegativeBalances)
+	prometheus.MustRegister(WalletReconcileCorrections)
+	prometheus.MustRegister(RefundProcessed)
+	prometheus.MustRegister(RefundFailed)
+	prometheus.MustRegister(BalanceAlerts)
+	prometheus.MustRegister(CircuitBreakerState)
+	prometheus.MustRegister(CircuitBreakerRejected)
+	prometheus.MustRegister(ParkedMessages)
+	prometheus.MustRegister(ParkedRepublished)
+	prometheus.MustRegister(OTPEvents)
+	prometheus.MustRegister(CampaignMessages)
+	prometheus.MustRegister(MessagesExpired)
+	prometheus.MustRegister(InboundMessages)
+	prometheus.MustRegister(InboundWebhooks)
+	prometheus.MustRegister(AutoReplies)
+	prometheus.MustRegister(LinkClicks)
+	prometheus.MustRegister(QuotaRejections)
+	prometheus.MustRegister(ProviderThrottleWait)
+	prometheus.MustRegister(UnpaidCharges)
+}
+
+// Serve exposes /metrics on its own port so it is not reachable through the
+// public API listener.
+func Serve(port string) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	go func() {
+		logger.Info("Metrics server started", zap.String("port", port))
+		if err := http.ListenAndServe(":"+port, mux); err != nil {
+			logger.Error("Metrics server failed", zap.String("port", port), zap.Error(err))
+		}
+	}()
 }
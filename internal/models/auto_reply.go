@@ -0,0 +1,63 @@
+package models
+
+import "time"
+
+// MatchType decides how an auto-reply rule's keyword is compared with an
+// inbound text. Texts and keywords are compared upper-cased.
+type MatchType string
+
+const (
+	MatchExact    MatchType = "exact"    // the whole text is the keyword
+	MatchPrefix   MatchType = "prefix"   // the first word is the keyword
+	MatchContains MatchType = "contains" // the text contains the keyword
+	MatchAny      MatchType = "any"      // every text, keyword empty
+)
+
+// AutoReplyAction is done for the sender when a rule matches, besides the
+// reply.
+type AutoReplyAction string
+
+const (
+	ActionNone   AutoReplyAction = ""
+	ActionOptOut AutoReplyAction = "opt_out" // stop marketing messages to the sender
+	ActionOptIn  AutoReplyAction = "opt_in"  // undo an opt-out
+	ActionTag    AutoReplyAction = "tag"     // tag the sender with Tag
+)
+
+type AutoReplyRule struct {
+	ID              int64           `json:"id"`
+	UserID          string          `json:"user_id"`
+	Number          string          `json:"number"`
+	MatchType       MatchType       `json:"match_type"`
+	Keyword         string          `json:"keyword,omitempty"`
+	Reply           string          `json:"reply,omitempty"`
+	Action          AutoReplyAction `json:"action,omitempty"`
+	Tag             string          `json:"tag,omitempty"`
+	CooldownSeconds int             `json:"cooldown_seconds"`
+	CreatedAt       time.Time       `json:"created_at"`
+}
+
+// AutoReplyRuleRequest creates a rule. Reply may use {from}, {to} and
+// {keyword}; a rule needs a reply, an action or both.
+type AutoReplyRuleRequest struct {
+	UserID          string          `json:"user_id"`
+	Number          string          `json:"number"`
+	MatchType       MatchType       `json:"match_type" enums:"exact,prefix,contains,any"`
+	Keyword         string          `json:"keyword,omitempty"`
+	Reply           string          `json:"reply,omitempty"`
+	Action          AutoReplyAction `json:"action,omitempty" enums:"opt_out,opt_in,tag"`
+	Tag             string          `json:"tag,omitempty"`
+	CooldownSeconds int             `json:"cooldown_seconds,omitempty"`
+}
+
+type OptOut struct {
+	PhoneNumber string    `json:"phone_number"`
+	RuleID      *int64    `json:"rule_id,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type ContactTag struct {
+	PhoneNumber string    `json:"phone_number"`
+	Tag         string    `json:"tag"`
+	CreatedAt   time.Time `json:"created_at"`
+}
@@ -0,0 +1,11 @@
+package models
+
+// BalanceAlertConfig configures low-balance notifications for a user.
+// An alert fires when the balance drops to or below one of the thresholds,
+// and is re-armed once the balance climbs above threshold + hysteresis.
+type BalanceAlertConfig struct {
+	Thresholds  []int64 `json:"thresholds"`
+	Hysteresis  int64   `json:"hysteresis"`
+	NotifyPhone string  `json:"notify_phone,omitempty"`
+	WebhookURL  string  `json:"webhook_url,omitempty"`
+}
@@ -0,0 +1,52 @@
+package models
+
+import "time"
+
+type CampaignStatus string
+
+const (
+	CampaignDraft     CampaignStatus = "draft"
+	CampaignRunning   CampaignStatus = "running"
+	CampaignPaused    CampaignStatus = "paused"
+	CampaignCompleted CampaignStatus = "completed"
+	CampaignCancelled CampaignStatus = "cancelled"
+)
+
+// CampaignCreateRequest creates a draft campaign. Body may reference CSV
+// columns as {column}, e.g. "Hi {name}, ...".
+type CampaignCreateRequest struct {
+	UserID        string `json:"user_id"`
+	Name          string `json:"name"`
+	Body          string `json:"body"`
+	RatePerSecond int    `json:"rate_per_second,omitempty"`
+	ShortenLinks  bool   `json:"shorten_links,omitempty"`
+}
+
+type Campaign struct {
+	ID            string         `json:"id"`
+	UserID        string         `json:"user_id"`
+	Name          string         `json:"name"`
+	Body          string         `json:"body"`
+	Status        CampaignStatus `json:"status"`
+	RatePerSecond int            `json:"rate_per_second"`
+	ShortenLinks  bool           `json:"shorten_links"`
+	LastError     string         `json:"last_error,omitempty"`
+	CreatedAt     time.Time      `json:"created_at"`
+	StartedAt     *time.Time     `json:"started_at,omitempty"`
+	FinishedAt    *time.Time     `json:"finished_at,omitempty"`
+}
+
+// CampaignRecipient is one uploaded CSV row.
+type CampaignRecipient struct {
+	PhoneNumber string
+	Variables   map[string]string
+	MessageID   string
+}
+
+// CampaignProgress counts a campaign's recipients: Pending have not been
+// dispatched yet, Messages counts the dispatched ones by message status.
+type CampaignProgress struct {
+	Total    int64            `json:"total"`
+	Pending  int64            `json:"pending"`
+	Messages map[string]int64 `json:"messages"`
+}
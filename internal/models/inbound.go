@@ -0,0 +1,46 @@
+package models
+
+import "time"
+
+// InboundRoute sends mobile-originated messages for Number, and starting
+// with Keyword if it is set, to UserID.
+type InboundRoute struct {
+	ID         int64     `json:"id"`
+	Number     string    `json:"number"`
+	Keyword    string    `json:"keyword,omitempty"`
+	UserID     string    `json:"user_id"`
+	WebhookURL string    `json:"webhook_url,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type InboundRouteRequest struct {
+	Number     string `json:"number"`
+	Keyword    string `json:"keyword,omitempty"`
+	UserID     string `json:"user_id"`
+	WebhookURL string `json:"webhook_url,omitempty"`
+}
+
+// ProviderInbound is one mobile-originated message as a provider reports it.
+// MessageID is the provider's id; a message reported twice is stored once.
+type ProviderInbound struct {
+	MessageID  string     `json:"message_id"`
+	From       string     `json:"from"`
+	To         string     `json:"to"`
+	Text       string     `json:"text"`
+	ReceivedAt *time.Time `json:"received_at,omitempty"`
+}
+
+type InboundMessage struct {
+	ID                string    `json:"id"`
+	Seq               int64     `json:"seq"`
+	UserID            string    `json:"user_id,omitempty"`
+	Provider          string    `json:"provider"`
+	ProviderMessageID string    `json:"provider_message_id"`
+	From              string    `json:"from"`
+	To                string    `json:"to"`
+	Text              string    `json:"text"`
+	Keyword           string    `json:"keyword,omitempty"`
+	WebhookStatus     string    `json:"webhook_status"` // none, pending, delivered or failed
+	ReplyMessageID    string    `json:"reply_message_id,omitempty"`
+	ReceivedAt        time.Time `json:"received_at"`
+}
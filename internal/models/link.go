@@ -0,0 +1,40 @@
+package models
+
+import "time"
+
+// ShortLink maps Token, the last path segment of a short URL, to the URL it
+// replaced in one message.
+type ShortLink struct {
+	Token string `json:"token"`
+	URL   string `json:"url"`
+}
+
+type LinkClicks struct {
+	ShortLink
+	Clicks       int64      `json:"clicks"`
+	FirstClickAt *time.Time `json:"first_click_at,omitempty"`
+	LastClickAt  *time.Time `json:"last_click_at,omitempty"`
+}
+
+type MessageClickReport struct {
+	MessageID   string       `json:"message_id"`
+	TotalClicks int64        `json:"total_clicks"`
+	Links       []LinkClicks `json:"links"`
+}
+
+type URLClicks struct {
+	URL             string `json:"url"`
+	Clicks          int64  `json:"clicks"`
+	MessagesClicked int64  `json:"messages_clicked"`
+}
+
+// CampaignClickReport counts clicks on a campaign's short links. ClickRate is
+// MessagesClicked over Messages, the messages that got short links.
+type CampaignClickReport struct {
+	CampaignID      string      `json:"campaign_id"`
+	Messages        int64       `json:"messages"`
+	MessagesClicked int64       `json:"messages_clicked"`
+	TotalClicks     int64       `json:"total_clicks"`
+	ClickRate       float64     `json:"click_rate"`
+	URLs            []URLClicks `json:"urls"`
+}
@@ -0,0 +1,18 @@
+package models
+
+// OTPSendRequest asks the gateway to generate a code and send it. Length,
+// TTLSeconds and Template fall back to the configured defaults; Template must
+// contain {code}.
+type OTPSendRequest struct {
+	UserID      string `json:"user_id"`
+	PhoneNumber string `json:"phone_number"`
+	Length      int    `json:"length,omitempty"`
+	TTLSeconds  int    `json:"ttl_seconds,omitempty"`
+	Template    string `json:"template,omitempty"`
+}
+
+type OTPVerifyRequest struct {
+	UserID      string `json:"user_id"`
+	PhoneNumber string `json:"phone_number"`
+	Code        string `json:"code"`
+}
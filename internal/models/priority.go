@@ -0,0 +1,37 @@
+package models
+
+// Priority is the class of a message. Each class has its own Kafka topic so
+// workers can serve OTPs ahead of bulk traffic.
+type Priority string
+
+const (
+	PriorityOTP           Priority = "otp"
+	PriorityTransactional Priority = "transactional"
+	PriorityMarketing     Priority = "marketing"
+)
+
+// Priorities lists every class, highest first.
+var Priorities = []Priority{PriorityOTP, PriorityTransactional, PriorityMarketing}
+
+// ParsePriority accepts a class name; an empty one means transactional.
+func ParsePriority(s string) (Priority, bool) {
+	if s == "" {
+		return PriorityTransactional, true
+	}
+	for _, p := range Priorities {
+		if string(p) == s {
+			return p, true
+		}
+	}
+	return "", false
+}
+
+// Topic returns the class's topic for a base topic such as sms-normal.
+// Transactional traffic keeps the base topic; the other classes use
+// <base>-<class>.
+func (p Priority) Topic(base string) string {
+	if p == "" || p == PriorityTransactional {
+		return base
+	}
+	return base + "-" + string(p)
+}
@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+type QuotaPeriod string
+
+const (
+	QuotaDay   QuotaPeriod = "day"
+	QuotaMonth QuotaPeriod = "month"
+)
+
+// Quota caps the messages a user may send per calendar day or month, of one
+// priority class or, with Priority empty, of all classes together.
+type Quota struct {
+	UserID   string      `json:"user_id"`
+	Priority Priority    `json:"priority,omitempty" enums:"otp,transactional,marketing"`
+	Period   QuotaPeriod `json:"period" enums:"day,month"`
+	Limit    int64       `json:"limit"`
+}
+
+type QuotaUsage struct {
+	Quota
+	Used      int64     `json:"used"`
+	Remaining int64     `json:"remaining"`
+	ResetsAt  time.Time `json:"resets_at"`
+}
@@ -2,6 +2,7 @@ package models
 
 import (
 	"encoding/json"
+	"time"
 )
 
 type SMSRequest struct {
@@ -9,9 +10,34 @@ type SMSRequest struct {
 	PhoneNumber string `json:"phone_number"`
 	Message     string `json:"message"`
 	MessageID   string `json:"message_id"`
+	// SenderID is the originator shown on the handset: up to 11 letters and
+	// digits, or a number. Empty uses the provider's default.
+	SenderID string `json:"sender_id,omitempty"`
+	// Priority is otp, transactional (default) or marketing.
+	Priority Priority `json:"priority,omitempty" enums:"otp,transactional,marketing"`
+	// Validity in seconds or ExpiresAt bound how long the message may wait
+	// before it is sent; without either the priority's default applies.
+	Validity  int        `json:"validity,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	// ShortenLinks replaces URLs in Message with tracked short links.
+	ShortenLinks bool `json:"shorten_links,omitempty"`
+	// CampaignID is set by the campaign dispatcher only.
+	CampaignID string `json:"-"`
+	// Links are the short links in Message, stored with the message.
+	Links []ShortLink `json:"-"`
+	// OTPMasked is set for /otp/send messages: it is stored in place of
+	// Message, which carries the code, so the code only travels to the worker.
+	OTPMasked string `json:"-"`
 }
 
 func (s *SMSRequest) ToJSON() string {
 	data, _ := json.Marshal(s)
 	return string(data)
 }
+
+// QueuedSMS is the Kafka payload produced by the gateway. ReservationID is set
+// for users whose balance was reserved up front; the worker settles it.
+type QueuedSMS struct {
+	SMSRequest
+	ReservationID string `json:"reservation_id,omitempty"`
+}
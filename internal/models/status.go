@@ -0,0 +1,56 @@
+package models
+
+import "time"
+
+type MessageStatus string
+
+const (
+	StatusQueued      MessageStatus = "queued"
+	StatusSending     MessageStatus = "sending"
+	StatusSent        MessageStatus = "sent"
+	StatusDelivered   MessageStatus = "delivered"
+	StatusUndelivered MessageStatus = "undelivered"
+	StatusFailed      MessageStatus = "failed"
+	StatusRejected    MessageStatus = "rejected"
+	StatusDeferred    MessageStatus = "deferred" // held until quiet hours end
+	StatusExpired     MessageStatus = "expired"  // validity elapsed before it was sent
+)
+
+// transitions lists, for every status, the statuses a message may move to.
+// Statuses without an entry are terminal.
+var transitions = map[MessageStatus][]MessageStatus{
+	StatusQueued:   {StatusSending, StatusFailed, StatusRejected, StatusDeferred, StatusExpired},
+	StatusSending:  {StatusSent, StatusFailed, StatusQueued}, // queued again when the provider could not be tried
+	StatusSent:     {StatusDelivered, StatusUndelivered},
+	StatusDeferred: {StatusQueued, StatusFailed, StatusExpired},
+}
+
+func CanTransition(from, to MessageStatus) bool {
+	for _, s := range transitions[from] {
+		if s == to {
+			return true
+		}
+	}
+	return false
+}
+
+// SourcesOf returns every status from which a message may move to the given one.
+func SourcesOf(to MessageStatus) []string {
+	var sources []string
+	for from, targets := range transitions {
+		for _, s := range targets {
+			if s == to {
+				sources = append(sources, string(from))
+			}
+		}
+	}
+	return sources
+}
+
+// MessageEvent is one recorded status transition of a message.
+type MessageEvent struct {
+	From      MessageStatus `json:"from,omitempty"`
+	To        MessageStatus `json:"to"`
+	Reason    string        `json:"reason,omitempty"`
+	CreatedAt time.Time     `json:"at"`
+}
@@ -0,0 +1,188 @@
+package otp
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"math/big"
+	"time"
+
+	"arvan-sms-gateway/internal/breaker"
+	"arvan-sms-gateway/internal/logger"
+	"arvan-sms-gateway/internal/tracing"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrCooldown        = errors.New("otp resend cooldown active")
+	ErrNotFound        = errors.New("no active otp for this phone number")
+	ErrMismatch        = errors.New("otp does not match")
+	ErrTooManyAttempts = errors.New("too many otp attempts")
+)
+
+// attempt counts one verification attempt and returns the attempts so far
+// with the stored message id and hash. Past the limit the code is dropped.
+var attempt = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 0 then
+    return nil
+end
+local n = redis.call('HINCRBY', KEYS[1], 'attempts', 1)
+if n > tonumber(ARGV[1]) then
+    redis.call('DEL', KEYS[1])
+    return {n, '', ''}
+end
+return {n, redis.call('HGET', KEYS[1], 'message_id'), redis.call('HGET', KEYS[1], 'hash')}`)
+
+// consume deletes the code only if it is still the one that was checked, so
+// a code is accepted at most once.
+var consume = redis.NewScript(`
+if redis.call('HGET', KEYS[1], 'message_id') == ARGV[1] then
+    return redis.call('DEL', KEYS[1])
+end
+return 0`)
+
+var rdb *redis.Client
+
+func Init(addr string) {
+	rdb = redis.NewClient(&redis.Options{Addr: addr, ContextTimeoutEnabled: true})
+	tracing.InstrumentRedis(rdb)
+	logger.Info("OTP store initialized")
+}
+
+func codeKey(userID, phone string) string {
+	return "otp:" + userID + ":" + phone
+}
+
+func cooldownKey(userID, phone string) string {
+	return "otp_cooldown:" + userID + ":" + phone
+}
+
+// hash binds the code to its message so equal codes never share a hash.
+func hash(messageID, code string) string {
+	sum := sha256.Sum256([]byte(messageID + ":" + code))
+	return hex.EncodeToString(sum[:])
+}
+
+// Generate returns a uniformly random numeric code.
+func Generate(length int) (string, error) {
+	code := make([]byte, length)
+	for i := range code {
+		n, err := rand.Int(rand.Reader, big.NewInt(10))
+		if err != nil {
+			return "", err
+		}
+		code[i] = byte('0' + n.Int64())
+	}
+	return string(code), nil
+}
+
+// Issue stores the hash of code for userID and phone, replacing any earlier
+// code. It returns ErrCooldown and the remaining wait while the previous code
+// for the same phone is younger than cooldown; a zero cooldown disables it.
+func Issue(ctx context.Context, userID, phone, code, messageID string, ttl, cooldown time.Duration) (time.Duration, error) {
+	var wait time.Duration
+	cooling := false
+	err := breaker.Redis().Do(ctx, func(ctx context.Context) error {
+		if cooldown <= 0 {
+			// SETNX without an expiry would lock the phone out for good
+			return nil
+		}
+		ok, err := rdb.SetNX(ctx, cooldownKey(userID, phone), messageID, cooldown).Result()
+		if err != nil || ok {
+			return err
+		}
+		cooling = true
+		wait, err = rdb.PTTL(ctx, cooldownKey(userID, phone)).Result()
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	if cooling {
+		return max(wait, 0), ErrCooldown
+	}
+
+	err = breaker.Redis().Do(ctx, func(ctx context.Context) error {
+		_, err := rdb.TxPipelined(ctx, func(p redis.Pipeliner) error {
+			p.Del(ctx, codeKey(userID, phone))
+			p.HSet(ctx, codeKey(userID, phone), "hash", hash(messageID, code), "message_id", messageID, "attempts", 0)
+			p.Expire(ctx, codeKey(userID, phone), ttl)
+			return nil
+		})
+		return err
+	})
+	return 0, err
+}
+
+// Cancel removes a code whose SMS could not be sent, and its cooldown.
+func Cancel(ctx context.Context, userID, phone string) {
+	err := breaker.Redis().Do(ctx, func(ctx context.Context) error {
+		return rdb.Del(ctx, codeKey(userID, phone), cooldownKey(userID, phone)).Err()
+	})
+	if err != nil {
+		logger.WarnCtx(ctx, "Failed to cancel OTP", zap.Error(err))
+	}
+}
+
+// Verify checks code against the active code of userID and phone. A matching
+// code is consumed and its message id returned; a wrong one returns
+// ErrMismatch and the attempts left. The code is dropped once maxAttempts
+// wrong codes were tried.
+func Verify(ctx context.Context, userID, phone, code string, maxAttempts int) (string, int, error) {
+	key := codeKey(userID, phone)
+
+	var res []interface{}
+	err := breaker.Redis().Do(ctx, func(ctx context.Context) error {
+		v, err := attempt.Run(ctx, rdb, []string{key}, maxAttempts).Slice()
+		if err == redis.Nil {
+			return nil
+		}
+		res = v
+		return err
+	})
+	if err != nil {
+		return "", 0, err
+	}
+	if res == nil {
+		return "", 0, ErrNotFound
+	}
+	attempts, _ := res[0].(int64)
+	messageID, _ := res[1].(string)
+	stored, _ := res[2].(string)
+	if messageID == "" {
+		return "", 0, ErrTooManyAttempts
+	}
+
+	left := maxAttempts - int(attempts)
+	if subtle.ConstantTimeCompare([]byte(hash(messageID, code)), []byte(stored)) != 1 {
+		if left > 0 {
+			return "", left, ErrMismatch
+		}
+		// last attempt used up: drop the code now rather than on the next try
+		_ = consumeCode(ctx, key, messageID)
+		return "", 0, ErrTooManyAttempts
+	}
+
+	if err := consumeCode(ctx, key, messageID); err != nil {
+		return "", 0, err
+	}
+	return messageID, left, nil
+}
+
+func consumeCode(ctx context.Context, key, messageID string) error {
+	var n int
+	err := breaker.Redis().Do(ctx, func(ctx context.Context) error {
+		var err error
+		n, err = consume.Run(ctx, rdb, []string{key}, messageID).Int()
+		return err
+	})
+	if err == nil && n == 0 {
+		// verified concurrently by another request
+		return ErrNotFound
+	}
+	return err
+}
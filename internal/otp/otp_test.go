@@ -0,0 +1,72 @@
+package otp
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func startRedis(t *testing.T) *miniredis.Miniredis {
+	t.Helper()
+	srv := miniredis.RunT(t)
+	rdb = redis.NewClient(&redis.Options{Addr: srv.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+	return srv
+}
+
+func TestIssueCooldown(t *testing.T) {
+	srv := startRedis(t)
+	ctx := context.Background()
+
+	if _, err := Issue(ctx, "u1", "09120000000", "1234", "m1", time.Minute, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	wait, err := Issue(ctx, "u1", "09120000000", "5678", "m2", time.Minute, time.Minute)
+	if !errors.Is(err, ErrCooldown) || wait <= 0 || wait > time.Minute {
+		t.Fatalf("resend within cooldown: wait = %s, err = %v; want ErrCooldown", wait, err)
+	}
+
+	srv.FastForward(time.Minute)
+	if _, err := Issue(ctx, "u1", "09120000000", "5678", "m3", time.Minute, time.Minute); err != nil {
+		t.Fatalf("resend after cooldown: %v", err)
+	}
+}
+
+func TestIssueWithoutCooldown(t *testing.T) {
+	srv := startRedis(t)
+	ctx := context.Background()
+
+	for i, id := range []string{"m1", "m2", "m3"} {
+		if _, err := Issue(ctx, "u1", "09120000000", "1234", id, time.Minute, 0); err != nil {
+			t.Fatalf("send %d: %v", i, err)
+		}
+	}
+	if srv.Exists(cooldownKey("u1", "09120000000")) {
+		t.Fatal("a zero cooldown left a cooldown key behind")
+	}
+	if id, _, err := Verify(ctx, "u1", "09120000000", "1234", 3); err != nil || id != "m3" {
+		t.Fatalf("verify = %q, %v; want the latest code's message m3", id, err)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	startRedis(t)
+	ctx := context.Background()
+
+	if _, err := Issue(ctx, "u1", "09120000000", "1234", "m1", time.Minute, 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, left, err := Verify(ctx, "u1", "09120000000", "0000", 2); !errors.Is(err, ErrMismatch) || left != 1 {
+		t.Fatalf("wrong code: left = %d, err = %v; want ErrMismatch with 1 left", left, err)
+	}
+	if _, _, err := Verify(ctx, "u1", "09120000000", "0000", 2); !errors.Is(err, ErrTooManyAttempts) {
+		t.Fatalf("last wrong code: err = %v; want ErrTooManyAttempts", err)
+	}
+	if _, _, err := Verify(ctx, "u1", "09120000000", "1234", 2); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("right code after lockout: err = %v; want ErrNotFound", err)
+	}
+}
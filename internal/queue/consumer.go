@@ -0,0 +1,45 @@
+package queue
+
+import (
+	"fmt"
+
+	"github.com/IBM/sarama"
+)
+
+// consumerVersion is the protocol version used by workers and the offsets
+// admin tool. Static membership (InstanceId) needs at least 2.3.
+var consumerVersion = sarama.V2_5_0_0
+
+// ParseInitialOffset maps the KAFKA_INITIAL_OFFSET setting to a sarama offset.
+// It only applies to partitions for which the group has no committed offset.
+func ParseInitialOffset(v string) (int64, error) {
+	switch v {
+	case "", "oldest", "earliest":
+		return sarama.OffsetOldest, nil
+	case "newest", "latest":
+		return sarama.OffsetNewest, nil
+	}
+	return 0, fmt.Errorf("invalid initial offset %q (want oldest/earliest or newest/latest)", v)
+}
+
+// NewConsumerGroup joins a stable, named consumer group. Every replica started
+// with the same group ID shares the topic's partitions, and committed offsets
+// survive restarts so a worker resumes where the group left off.
+func NewConsumerGroup(brokers []string, group, instanceID, initialOffset string) (sarama.ConsumerGroup, error) {
+	if group == "" {
+		return nil, fmt.Errorf("consumer group id is required")
+	}
+	initial, err := ParseInitialOffset(initialOffset)
+	if err != nil {
+		return nil, err
+	}
+
+	config := sarama.NewConfig()
+	config.Version = consumerVersion
+	config.Consumer.Group.Rebalance.GroupStrategies = []sarama.BalanceStrategy{sarama.NewBalanceStrategySticky()}
+	config.Consumer.Group.InstanceId = instanceID
+	config.Consumer.Offsets.Initial = initial
+	config.Consumer.Offsets.AutoCommit.Enable = false
+
+	return sarama.NewConsumerGroup(brokers, group, config)
+}
@@ -0,0 +1,96 @@
+package queue
+
+import (
+	"context"
+
+	"arvan-sms-gateway/internal/logger"
+	"github.com/IBM/sarama"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// requestIDHeader carries the gateway request ID to the worker's logs.
+const requestIDHeader = "request_id"
+
+// producerHeaders and consumerHeaders adapt Kafka record headers to the
+// OpenTelemetry propagation carrier.
+type producerHeaders struct {
+	msg *sarama.ProducerMessage
+}
+
+func (h producerHeaders) Get(key string) string {
+	for _, hdr := range h.msg.Headers {
+		if string(hdr.Key) == key {
+			return string(hdr.Value)
+		}
+	}
+	return ""
+}
+
+func (h producerHeaders) Set(key, value string) {
+	for i, hdr := range h.msg.Headers {
+		if string(hdr.Key) == key {
+			h.msg.Headers[i].Value = []byte(value)
+			return
+		}
+	}
+	h.msg.Headers = append(h.msg.Headers, sarama.RecordHeader{Key: []byte(key), Value: []byte(value)})
+}
+
+func (h producerHeaders) Keys() []string {
+	keys := make([]string, len(h.msg.Headers))
+	for i, hdr := range h.msg.Headers {
+		keys[i] = string(hdr.Key)
+	}
+	return keys
+}
+
+type consumerHeaders []*sarama.RecordHeader
+
+func (h consumerHeaders) Get(key string) string {
+	for _, hdr := range h {
+		if string(hdr.Key) == key {
+			return string(hdr.Value)
+		}
+	}
+	return ""
+}
+
+func (h consumerHeaders) Set(string, string) {}
+
+func (h consumerHeaders) Keys() []string {
+	keys := make([]string, len(h))
+	for i, hdr := range h {
+		keys[i] = string(hdr.Key)
+	}
+	return keys
+}
+
+// ExtractContext returns ctx carrying the trace context and request ID the
+// producer stored in msg's headers, if any.
+func ExtractContext(ctx context.Context, msg *sarama.ConsumerMessage) context.Context {
+	headers := consumerHeaders(msg.Headers)
+	if id := headers.Get(requestIDHeader); id != "" {
+		ctx = logger.WithRequestID(ctx, id)
+	}
+	return otel.GetTextMapPropagator().Extract(ctx, headers)
+}
+
+// Headers returns the headers SendMessage would write for ctx, so a message
+// stored outside Kafka keeps its trace context and request ID.
+func Headers(ctx context.Context) map[string]string {
+	headers := propagation.MapCarrier{}
+	otel.GetTextMapPropagator().Inject(ctx, headers)
+	if id := logger.RequestID(ctx); id != "" {
+		headers[requestIDHeader] = id
+	}
+	return headers
+}
+
+// WithHeaders is the inverse of Headers.
+func WithHeaders(ctx context.Context, headers map[string]string) context.Context {
+	if id := headers[requestIDHeader]; id != "" {
+		ctx = logger.WithRequestID(ctx, id)
+	}
+	return otel.GetTextMapPropagator().Extract(ctx, propagation.MapCarrier(headers))
+}
@@ -1,14 +1,26 @@
 package queue
 
 import (
+	"context"
+	"errors"
+
+	"arvan-sms-gateway/internal/breaker"
 	"arvan-sms-gateway/internal/logger"
 	"arvan-sms-gateway/internal/metrics"
+	"arvan-sms-gateway/internal/tracing"
 
 	"github.com/IBM/sarama"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 )
 
-var producer sarama.SyncProducer
+var (
+	client   sarama.Client
+	producer sarama.SyncProducer
+)
 
 func InitKafka(brokers []string) error {
 	config := sarama.NewConfig()
@@ -18,7 +30,12 @@ func InitKafka(brokers []string) error {
 	config.Version = sarama.V3_6_0_0
 
 	var err error
-	producer, err = sarama.NewSyncProducer(brokers, config)
+	client, err = sarama.NewClient(brokers, config)
+	if err != nil {
+		logger.Error("Kafka producer initialization failed", zap.Error(err))
+		return err
+	}
+	producer, err = sarama.NewSyncProducerFromClient(client)
 	if err != nil {
 		logger.Error("Kafka producer initialization failed", zap.Error(err))
 		return err
@@ -27,16 +44,38 @@ func InitKafka(brokers []string) error {
 	return nil
 }
 
-func SendMessage(topic string, key, value string) error {
+// SendMessage produces one record and carries the caller's trace context in
+// the record headers, so the consuming worker continues the same trace. It
+// fails fast with breaker.ErrOpen while the Kafka breaker is open.
+func SendMessage(ctx context.Context, topic string, key, value string) error {
+	ctx, span := tracing.Tracer("queue").Start(ctx, "kafka.produce "+topic,
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(
+			attribute.String("messaging.system", "kafka"),
+			attribute.String("messaging.destination.name", topic)))
+	defer span.End()
+
 	msg := &sarama.ProducerMessage{
 		Topic: topic,
 		Key:   sarama.StringEncoder(key),
 		Value: sarama.StringEncoder(value),
 	}
+	otel.GetTextMapPropagator().Inject(ctx, producerHeaders{msg})
+	if id := logger.RequestID(ctx); id != "" {
+		producerHeaders{msg}.Set(requestIDHeader, id)
+	}
 
-	partition, offset, err := producer.SendMessage(msg)
+	var partition int32
+	var offset int64
+	err := breaker.Kafka().Do(ctx, func(context.Context) error {
+		var err error
+		partition, offset, err = producer.SendMessage(msg)
+		return err
+	})
 	if err != nil {
-		logger.Error("Failed to send message to Kafka",
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		logger.ErrorCtx(ctx, "Failed to send message to Kafka",
 			zap.String("topic", topic),
 			zap.String("key", key),
 			zap.Error(err),
@@ -45,19 +84,41 @@ func SendMessage(topic string, key, value string) error {
 		return err
 	}
 
-	logger.Info("Message sent to Kafka",
+	logger.InfoCtx(ctx, "Message sent to Kafka",
 		zap.String("topic", topic),
 		zap.String("key", key),
 		zap.Int32("partition", partition),
 		zap.Int64("offset", offset),
 	)
+	span.SetAttributes(
+		attribute.Int("messaging.kafka.destination.partition", int(partition)),
+		attribute.Int64("messaging.kafka.message.offset", offset))
 	metrics.KafkaMessages.Inc()
 	return nil
 }
 
+// Ping refreshes the cluster metadata through the producer's client, which
+// fails when no broker is reachable.
+func Ping(ctx context.Context) error {
+	if client == nil || client.Closed() {
+		return errors.New("kafka producer not initialized")
+	}
+	done := make(chan error, 1)
+	go func() { done <- client.RefreshMetadata() }()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
 func Close() {
 	if producer != nil {
 		_ = producer.Close()
 		logger.Info("Kafka producer closed")
 	}
+	if client != nil {
+		_ = client.Close()
+	}
 }
@@ -0,0 +1,183 @@
+package queue
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/IBM/sarama"
+)
+
+// PartitionOffset describes where a consumer group stands on one partition.
+type PartitionOffset struct {
+	Partition int32
+	Committed int64 // -1 when the group never committed on this partition
+	Oldest    int64
+	Newest    int64
+	Target    int64 // only set by ResetGroupOffsets
+}
+
+// Lag is the number of messages the group still has to consume.
+func (p PartitionOffset) Lag() int64 {
+	if p.Committed < 0 {
+		return p.Newest - p.Oldest
+	}
+	return p.Newest - p.Committed
+}
+
+func newAdminClient(brokers []string) (sarama.Client, sarama.ClusterAdmin, error) {
+	config := sarama.NewConfig()
+	config.Version = consumerVersion
+	config.Consumer.Offsets.AutoCommit.Enable = false
+
+	client, err := sarama.NewClient(brokers, config)
+	if err != nil {
+		return nil, nil, err
+	}
+	admin, err := sarama.NewClusterAdminFromClient(client)
+	if err != nil {
+		client.Close()
+		return nil, nil, err
+	}
+	return client, admin, nil
+}
+
+func groupOffsets(client sarama.Client, admin sarama.ClusterAdmin, group, topic string) ([]PartitionOffset, error) {
+	partitions, err := client.Partitions(topic)
+	if err != nil {
+		return nil, err
+	}
+	committed, err := admin.ListConsumerGroupOffsets(group, map[string][]int32{topic: partitions})
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]PartitionOffset, 0, len(partitions))
+	for _, p := range partitions {
+		oldest, err := client.GetOffset(topic, p, sarama.OffsetOldest)
+		if err != nil {
+			return nil, err
+		}
+		newest, err := client.GetOffset(topic, p, sarama.OffsetNewest)
+		if err != nil {
+			return nil, err
+		}
+		po := PartitionOffset{Partition: p, Committed: -1, Oldest: oldest, Newest: newest}
+		if block := committed.GetBlock(topic, p); block != nil {
+			po.Committed = block.Offset
+		}
+		result = append(result, po)
+	}
+	return result, nil
+}
+
+// DescribeGroupOffsets reports committed offsets and lag for a group on a topic.
+func DescribeGroupOffsets(brokers []string, group, topic string) ([]PartitionOffset, error) {
+	client, admin, err := newAdminClient(brokers)
+	if err != nil {
+		return nil, err
+	}
+	defer admin.Close()
+
+	return groupOffsets(client, admin, group, topic)
+}
+
+// ResetGroupOffsets moves the committed offsets of group on topic to the given
+// target: "earliest", "latest", an absolute offset, or an RFC3339 timestamp.
+// Kafka rejects commits for groups with live members, so every replica of the
+// worker must be stopped first. When execute is false it only returns the plan.
+func ResetGroupOffsets(brokers []string, group, topic, to string, execute bool) ([]PartitionOffset, error) {
+	client, admin, err := newAdminClient(brokers)
+	if err != nil {
+		return nil, err
+	}
+	defer admin.Close()
+
+	desc, err := admin.DescribeConsumerGroups([]string{group})
+	if err != nil {
+		return nil, err
+	}
+	if len(desc) == 1 && len(desc[0].Members) > 0 {
+		return nil, fmt.Errorf("group %s has %d active members (state %s); stop all workers first",
+			group, len(desc[0].Members), desc[0].State)
+	}
+
+	offsets, err := groupOffsets(client, admin, group, topic)
+	if err != nil {
+		return nil, err
+	}
+	for i := range offsets {
+		target, err := resolveTarget(client, topic, offsets[i], to)
+		if err != nil {
+			return nil, err
+		}
+		offsets[i].Target = target
+	}
+	if !execute {
+		return offsets, nil
+	}
+
+	om, err := sarama.NewOffsetManagerFromClient(group, client)
+	if err != nil {
+		return nil, err
+	}
+	for _, po := range offsets {
+		pom, err := om.ManagePartition(topic, po.Partition)
+		if err != nil {
+			om.Close()
+			return nil, err
+		}
+		// MarkOffset only moves forward and ResetOffset only backward, and
+		// without a commit the manager starts below every real offset.
+		if po.Target > po.Committed {
+			pom.MarkOffset(po.Target, "reset by kafka-offsets")
+		} else {
+			pom.ResetOffset(po.Target, "reset by kafka-offsets")
+		}
+		pom.AsyncClose()
+	}
+	om.Commit()
+	if err := om.Close(); err != nil {
+		return nil, err
+	}
+
+	after, err := groupOffsets(client, admin, group, topic)
+	if err != nil {
+		return nil, fmt.Errorf("verify reset: %w", err)
+	}
+	for i, po := range after {
+		if po.Committed != offsets[i].Target {
+			return nil, fmt.Errorf("partition %d: committed offset is %d after reset, want %d",
+				po.Partition, po.Committed, offsets[i].Target)
+		}
+	}
+	return offsets, nil
+}
+
+func resolveTarget(client sarama.Client, topic string, po PartitionOffset, to string) (int64, error) {
+	switch to {
+	case "earliest", "oldest":
+		return po.Oldest, nil
+	case "latest", "newest":
+		return po.Newest, nil
+	}
+	if off, err := strconv.ParseInt(to, 10, 64); err == nil {
+		if off < po.Oldest || off > po.Newest {
+			return 0, fmt.Errorf("offset %d outside [%d, %d] on partition %d", off, po.Oldest, po.Newest, po.Partition)
+		}
+		return off, nil
+	}
+	ts, err := time.Parse(time.RFC3339, to)
+	if err != nil {
+		return 0, fmt.Errorf("invalid reset target %q (want earliest, latest, an offset or an RFC3339 time)", to)
+	}
+	off, err := client.GetOffset(topic, po.Partition, ts.UnixMilli())
+	if err != nil {
+		return 0, err
+	}
+	if off < 0 {
+		// nothing was produced after ts on this partition
+		return po.Newest, nil
+	}
+	return off, nil
+}
@@ -0,0 +1,164 @@
+// Package quiethours decides when marketing messages may be sent. A policy
+// has a default local window and optional windows per destination calling
+// code.
+package quiethours
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	// zone data for images without /usr/share/zoneinfo
+	_ "time/tzdata"
+)
+
+type window struct {
+	loc        *time.Location
+	start, end int // minutes after local midnight
+	off        bool
+}
+
+// until returns when the window that contains now ends, or the zero time
+// when now is outside it.
+func (w window) until(now time.Time) time.Time {
+	if w.off {
+		return time.Time{}
+	}
+	t := now.In(w.loc)
+	m := t.Hour()*60 + t.Minute()
+	quiet := w.start <= m && m < w.end
+	if w.start > w.end {
+		// overnight, e.g. 21:00-08:00
+		quiet = m >= w.start || m < w.end
+	}
+	if !quiet {
+		return time.Time{}
+	}
+	end := time.Date(t.Year(), t.Month(), t.Day(), w.end/60, w.end%60, 0, 0, w.loc)
+	if !end.After(t) {
+		end = time.Date(t.Year(), t.Month(), t.Day()+1, w.end/60, w.end%60, 0, 0, w.loc)
+	}
+	return end
+}
+
+type Policy struct {
+	def       window
+	countries map[string]window // calling code -> window
+}
+
+// Parse builds a policy from a window such as "21:00-08:00" (empty disables
+// quiet hours), the timezone it is read in, and comma separated overrides of
+// the form "CODE=TIMEZONE [WINDOW|off]", e.g.
+// "44=Europe/London 21:00-08:00,1=America/New_York". An override without a
+// window uses the default window in its own timezone.
+func Parse(hours, tz, overrides string) (*Policy, error) {
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		return nil, fmt.Errorf("unknown timezone %q", tz)
+	}
+	def, err := parseWindow(hours, loc)
+	if err != nil {
+		return nil, err
+	}
+	p := &Policy{def: def, countries: map[string]window{}}
+
+	for _, entry := range strings.Split(overrides, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		code, spec, ok := strings.Cut(entry, "=")
+		code = strings.TrimPrefix(strings.TrimSpace(code), "+")
+		if n, err := strconv.Atoi(code); !ok || err != nil || n < 1 || len(code) > 3 {
+			return nil, fmt.Errorf("override %q: expected CODE=TIMEZONE [WINDOW|off] with a 1 to 3 digit calling code", entry)
+		}
+		fields := strings.Fields(spec)
+		if len(fields) < 1 || len(fields) > 2 {
+			return nil, fmt.Errorf("override %q: expected CODE=TIMEZONE [WINDOW|off]", entry)
+		}
+		loc, err := time.LoadLocation(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("override %q: unknown timezone %q", entry, fields[0])
+		}
+		w := window{loc: loc, start: def.start, end: def.end, off: def.off}
+		if len(fields) == 2 {
+			if w, err = parseWindow(fields[1], loc); err != nil {
+				return nil, fmt.Errorf("override %q: %v", entry, err)
+			}
+		}
+		p.countries[code] = w
+	}
+	return p, nil
+}
+
+func parseWindow(s string, loc *time.Location) (window, error) {
+	if s == "" || s == "off" {
+		return window{loc: loc, off: true}, nil
+	}
+	from, to, ok := strings.Cut(s, "-")
+	start, err1 := parseClock(from)
+	end, err2 := parseClock(to)
+	if !ok || err1 != nil || err2 != nil || start == end {
+		return window{}, fmt.Errorf("invalid window %q (use HH:MM-HH:MM, e.g. 21:00-08:00)", s)
+	}
+	return window{loc: loc, start: start, end: end}, nil
+}
+
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, err
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// window picks the override matching the phone number's calling code, if
+// the number is in international format, or the default window.
+func (p *Policy) window(phone string) window {
+	phone = strings.NewReplacer(" ", "", "-", "", "(", "", ")", "").Replace(phone)
+	switch {
+	case strings.HasPrefix(phone, "+"):
+		phone = phone[1:]
+	case strings.HasPrefix(phone, "00"):
+		phone = phone[2:]
+	default:
+		return p.def
+	}
+	for n := 3; n >= 1; n-- {
+		if len(phone) >= n {
+			if w, ok := p.countries[phone[:n]]; ok {
+				return w
+			}
+		}
+	}
+	return p.def
+}
+
+// Until returns when quiet hours for phone end, or the zero time when a
+// marketing message may be sent to it at now.
+func (p *Policy) Until(phone string, now time.Time) time.Time {
+	return p.window(phone).until(now)
+}
+
+// DefaultUntil is Until for numbers without a country override.
+func (p *Policy) DefaultUntil(now time.Time) time.Time {
+	return p.def.until(now)
+}
+
+var policy = &Policy{def: window{off: true}}
+
+func Init(hours, tz, overrides string) error {
+	p, err := Parse(hours, tz, overrides)
+	if err != nil {
+		return err
+	}
+	policy = p
+	return nil
+}
+
+// Current returns the policy set by Init; before Init there are no quiet
+// hours.
+func Current() *Policy {
+	return policy
+}
@@ -0,0 +1,123 @@
+package quiethours
+
+import (
+	"testing"
+	"time"
+)
+
+func mustLoad(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return loc
+}
+
+func TestWindowUntil(t *testing.T) {
+	tehran := mustLoad(t, "Asia/Tehran")
+	overnight := window{loc: tehran, start: 21 * 60, end: 8 * 60}
+	daytime := window{loc: tehran, start: 12 * 60, end: 14 * 60}
+	at := func(day, hour, min int) time.Time {
+		return time.Date(2026, 5, day, hour, min, 0, 0, tehran)
+	}
+
+	for _, tc := range []struct {
+		name string
+		w    window
+		now  time.Time
+		want time.Time
+	}{
+		{"overnight before start", overnight, at(10, 20, 59), time.Time{}},
+		{"overnight at start", overnight, at(10, 21, 0), at(11, 8, 0)},
+		{"overnight before midnight", overnight, at(10, 23, 30), at(11, 8, 0)},
+		{"overnight after midnight", overnight, at(11, 0, 15), at(11, 8, 0)},
+		{"overnight last minute", overnight, at(11, 7, 59), at(11, 8, 0)},
+		{"overnight at end", overnight, at(11, 8, 0), time.Time{}},
+		{"daytime inside", daytime, at(10, 13, 0), at(10, 14, 0)},
+		{"daytime before", daytime, at(10, 11, 59), time.Time{}},
+		{"daytime at end", daytime, at(10, 14, 0), time.Time{}},
+		{"off", window{loc: tehran, off: true}, at(10, 23, 0), time.Time{}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got := tc.w.until(tc.now)
+			if !got.Equal(tc.want) {
+				t.Fatalf("until(%s) = %s, want %s", tc.now, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestWindowUntilAcrossDST(t *testing.T) {
+	london := mustLoad(t, "Europe/London")
+	w := window{loc: london, start: 21 * 60, end: 8 * 60}
+
+	for _, tc := range []struct {
+		name string
+		now  time.Time
+		want time.Time
+	}{
+		// clocks go forward at 01:00 UTC on 29 March 2026: the night is an hour shorter
+		{"spring forward", time.Date(2026, 3, 28, 22, 0, 0, 0, time.UTC), time.Date(2026, 3, 29, 7, 0, 0, 0, time.UTC)},
+		// clocks go back at 01:00 UTC on 25 October 2026: the night is an hour longer
+		{"fall back", time.Date(2026, 10, 24, 21, 0, 0, 0, time.UTC), time.Date(2026, 10, 25, 8, 0, 0, 0, time.UTC)},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got := w.until(tc.now)
+			if !got.Equal(tc.want) {
+				t.Fatalf("until(%s) = %s, want %s", tc.now, got.UTC(), tc.want)
+			}
+			if local := got.In(london); local.Hour() != 8 || local.Minute() != 0 {
+				t.Fatalf("window ends at %s local time, want 08:00", local)
+			}
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	for _, tc := range []struct {
+		name, hours, tz, overrides string
+		ok                         bool
+	}{
+		{"default", "21:00-08:00", "Asia/Tehran", "", true},
+		{"disabled", "", "Asia/Tehran", "", true},
+		{"overrides", "21:00-08:00", "Asia/Tehran", "44=Europe/London 20:00-09:00, +1=America/New_York,49=Europe/Berlin off", true},
+		{"unknown timezone", "21:00-08:00", "Mars/Olympus", "", false},
+		{"empty window", "21:00-21:00", "UTC", "", false},
+		{"bad clock", "25:00-08:00", "UTC", "", false},
+		{"code too long", "21:00-08:00", "UTC", "9999=UTC", false},
+		{"override timezone", "21:00-08:00", "UTC", "44=Europe/Nowhere", false},
+		{"override window", "21:00-08:00", "UTC", "44=Europe/London soon", false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := Parse(tc.hours, tc.tz, tc.overrides)
+			if (err == nil) != tc.ok {
+				t.Fatalf("Parse error = %v, want ok=%v", err, tc.ok)
+			}
+		})
+	}
+}
+
+func TestPolicyPicksOverrideByCallingCode(t *testing.T) {
+	p, err := Parse("21:00-08:00", "Asia/Tehran", "44=Europe/London 20:00-09:00,1=America/New_York off")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 21:30 in London, 01:00 in Tehran, 16:30 in New York
+	now := time.Date(2026, 1, 15, 21, 30, 0, 0, time.UTC)
+
+	for _, tc := range []struct {
+		phone string
+		quiet bool
+	}{
+		{"+44 7700 900123", true},
+		{"0044-7700-900123", true},
+		{"+1 (212) 555-0100", false},
+		{"+989121234567", true}, // no override: default window in Tehran
+		{"09121234567", true},   // national format uses the default window
+	} {
+		if got := !p.Until(tc.phone, now).IsZero(); got != tc.quiet {
+			t.Errorf("Until(%q) quiet = %v, want %v", tc.phone, got, tc.quiet)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+// Package quota counts messages against per-user send quotas in Redis. A
+// counter is kept per quota and calendar period and expires after it.
+package quota
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"arvan-sms-gateway/internal/breaker"
+	"arvan-sms-gateway/internal/logger"
+	"arvan-sms-gateway/internal/models"
+	"arvan-sms-gateway/internal/tracing"
+	"github.com/redis/go-redis/v9"
+)
+
+// take grants up to ARGV[1] messages against every counter in KEYS, limited
+// by the counter with the least room; ARGV[2] is 1 if fewer may be granted.
+// ARGV[3..] are the limit and TTL of each counter. It returns the messages
+// granted and the 1-based index of the limiting counter, 0 if none limited.
+var take = redis.NewScript(`
+local n = tonumber(ARGV[1])
+local grant, limiting = n, 0
+for i, key in ipairs(KEYS) do
+    local free = tonumber(ARGV[1 + 2 * i]) - tonumber(redis.call('GET', key) or '0')
+    if free < grant then
+        grant, limiting = math.max(free, 0), i
+    end
+end
+if grant < n and ARGV[2] ~= '1' then
+    return {0, limiting}
+end
+if grant > 0 then
+    for i, key in ipairs(KEYS) do
+        redis.call('INCRBY', key, grant)
+        redis.call('EXPIRE', key, ARGV[2 + 2 * i])
+    end
+end
+return {grant, limiting}`)
+
+// release gives back ARGV[1] messages to counters that still exist.
+var release = redis.NewScript(`
+for _, key in ipairs(KEYS) do
+    if redis.call('EXISTS', key) == 1 then
+        redis.call('DECRBY', key, ARGV[1])
+    end
+end
+return 0`)
+
+var (
+	rdb *redis.Client
+	loc = time.UTC
+)
+
+// Init connects the counters and sets the timezone whose calendar days and
+// months the quotas follow.
+func Init(addr, tz string) error {
+	l, err := time.LoadLocation(tz)
+	if err != nil {
+		return fmt.Errorf("unknown timezone %q", tz)
+	}
+	loc = l
+	rdb = redis.NewClient(&redis.Options{Addr: addr, ContextTimeoutEnabled: true})
+	tracing.InstrumentRedis(rdb)
+	logger.Info("Quota counters initialized")
+	return nil
+}
+
+// Window returns the id of the period containing now and when it ends.
+func Window(period models.QuotaPeriod, now time.Time) (string, time.Time) {
+	t := now.In(loc)
+	if period == models.QuotaMonth {
+		return t.Format("200601"), time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+	}
+	return t.Format("20060102"), time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+}
+
+func key(q models.Quota, now time.Time) string {
+	id, _ := Window(q.Period, now)
+	class := string(q.Priority)
+	if class == "" {
+		class = "all"
+	}
+	return "quota:" + q.UserID + ":" + class + ":" + string(q.Period) + ":" + id
+}
+
+// Take counts n messages at now against every quota. Without partial it
+// takes all n or none; with partial it takes as many as every quota allows.
+// It returns the messages taken and, when fewer than n, the quota that
+// limited them.
+func Take(ctx context.Context, quotas []models.Quota, n int64, partial bool, now time.Time) (int64, *models.Quota, error) {
+	if len(quotas) == 0 || rdb == nil {
+		return n, nil, nil
+	}
+	keys := make([]string, len(quotas))
+	args := []any{n, "0"}
+	if partial {
+		args[1] = "1"
+	}
+	for i, q := range quotas {
+		keys[i] = key(q, now)
+		_, end := Window(q.Period, now)
+		// kept a day past the period so a late release finds it
+		ttl := int64(time.Until(end.Add(24*time.Hour)) / time.Second)
+		args = append(args, q.Limit, ttl)
+	}
+
+	var res []int64
+	err := breaker.Redis().Do(ctx, func(ctx context.Context) error {
+		var err error
+		res, err = take.Run(ctx, rdb, keys, args...).Int64Slice()
+		return err
+	})
+	if err != nil {
+		return 0, nil, err
+	}
+	var limiting *models.Quota
+	if res[1] > 0 {
+		limiting = &quotas[res[1]-1]
+	}
+	return res[0], limiting, nil
+}
+
+// Release gives back n messages taken at takenAt that were not sent.
+func Release(ctx context.Context, quotas []models.Quota, n int64, takenAt time.Time) error {
+	if len(quotas) == 0 || n <= 0 || rdb == nil {
+		return nil
+	}
+	keys := make([]string, len(quotas))
+	for i, q := range quotas {
+		keys[i] = key(q, takenAt)
+	}
+	return breaker.Redis().Do(ctx, func(ctx context.Context) error {
+		return release.Run(ctx, rdb, keys, n).Err()
+	})
+}
+
+// Usage returns the messages counted against each quota in the period
+// containing now.
+func Usage(ctx context.Context, quotas []models.Quota, now time.Time) ([]int64, error) {
+	used := make([]int64, len(quotas))
+	if len(quotas) == 0 || rdb == nil {
+		return used, nil
+	}
+	keys := make([]string, len(quotas))
+	for i, q := range quotas {
+		keys[i] = key(q, now)
+	}
+	var vals []any
+	err := breaker.Redis().Do(ctx, func(ctx context.Context) error {
+		var err error
+		vals, err = rdb.MGet(ctx, keys...).Result()
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	for i, v := range vals {
+		if s, ok := v.(string); ok {
+			used[i], _ = strconv.ParseInt(s, 10, 64)
+		}
+	}
+	return used, nil
+}
@@ -0,0 +1,100 @@
+package ratelimit
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// idleTTL is how long an unused bucket is kept before it is dropped.
+const idleTTL = 10 * time.Minute
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// PerKey is an in-process token bucket per key (e.g. per user). The rate and
+// burst are passed on every call so they can change at runtime; buckets
+// refill at the new rate from then on.
+type PerKey struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	sweep   time.Time
+}
+
+func NewPerKey() *PerKey {
+	return &PerKey{buckets: map[string]*bucket{}}
+}
+
+// Allow takes one token from key's bucket. A rate of 0 or less disables the
+// limit.
+func (p *PerKey) Allow(key string, rate float64, burst int) bool {
+	if rate <= 0 {
+		return true
+	}
+	now := time.Now()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if now.Sub(p.sweep) > idleTTL {
+		for k, b := range p.buckets {
+			if now.Sub(b.last) > idleTTL {
+				delete(p.buckets, k)
+			}
+		}
+		p.sweep = now
+	}
+
+	b, ok := p.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(burst), last: now}
+		p.buckets[key] = b
+	}
+	b.tokens = min(float64(burst), b.tokens+now.Sub(b.last).Seconds()*rate)
+	b.last = now
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// Limit is a sustained rate in messages per second with a burst of
+// messages that may go out back to back.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// ParseLimits reads comma separated limits of the form NAME=RATE[:BURST],
+// e.g. "mock=100:200,mock/ACME=10". The burst defaults to one second of
+// traffic.
+func ParseLimits(spec string) (map[string]Limit, error) {
+	limits := map[string]Limit{}
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(entry, "=")
+		name = strings.TrimSpace(name)
+		rate, burst, hasBurst := strings.Cut(strings.TrimSpace(value), ":")
+		r, err := strconv.ParseFloat(rate, 64)
+		if !ok || name == "" || strings.HasPrefix(name, "/") || err != nil || r <= 0 || math.IsInf(r, 0) {
+			return nil, fmt.Errorf("limit %q: expected PROVIDER[/SENDER]=RATE[:BURST] with a positive rate", entry)
+		}
+		l := Limit{Rate: r, Burst: max(1, int(math.Ceil(r)))}
+		if hasBurst {
+			if l.Burst, err = strconv.Atoi(burst); err != nil || l.Burst < 1 {
+				return nil, fmt.Errorf("limit %q: burst must be a positive integer", entry)
+			}
+		}
+		limits[name] = l
+	}
+	return limits, nil
+}
@@ -0,0 +1,68 @@
+package ratelimit
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseLimits(t *testing.T) {
+	for _, tc := range []struct {
+		spec string
+		want map[string]Limit
+	}{
+		{"", map[string]Limit{}},
+		{" , ", map[string]Limit{}},
+		{"mock=100:200", map[string]Limit{"mock": {100, 200}}},
+		{"mock=100:200, mock/ACME=10", map[string]Limit{"mock": {100, 200}, "mock/ACME": {10, 10}}},
+		{"mock=2.5", map[string]Limit{"mock": {2.5, 3}}},
+		{"mock=0.2", map[string]Limit{"mock": {0.2, 1}}},
+		{" mock = 5:1 ", map[string]Limit{"mock": {5, 1}}},
+	} {
+		got, err := ParseLimits(tc.spec)
+		if err != nil {
+			t.Errorf("ParseLimits(%q): %v", tc.spec, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("ParseLimits(%q) = %v, want %v", tc.spec, got, tc.want)
+		}
+	}
+}
+
+func TestParseLimitsRejects(t *testing.T) {
+	for _, spec := range []string{
+		"mock",
+		"=10",
+		"/ACME=10",
+		"mock=",
+		"mock=0",
+		"mock=-1",
+		"mock=fast",
+		"mock=Inf",
+		"mock=10:0",
+		"mock=10:1.5",
+		"mock=10,other",
+	} {
+		if _, err := ParseLimits(spec); err == nil {
+			t.Errorf("ParseLimits(%q) accepted an invalid spec", spec)
+		}
+	}
+}
+
+func TestPerKeyAllow(t *testing.T) {
+	p := NewPerKey()
+	for i := 0; i < 3; i++ {
+		if !p.Allow("a", 0.001, 3) {
+			t.Fatalf("request %d within the burst was refused", i)
+		}
+	}
+	if p.Allow("a", 0.001, 3) {
+		t.Fatal("request beyond the burst was allowed")
+	}
+	if !p.Allow("b", 0.001, 3) {
+		t.Fatal("keys must not share a bucket")
+	}
+	if !p.Allow("a", 0, 3) {
+		t.Fatal("a rate of 0 must disable the limit")
+	}
+}
@@ -0,0 +1,137 @@
+package reservation
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"arvan-sms-gateway/internal/alerts"
+	"arvan-sms-gateway/internal/logger"
+	"go.uber.org/zap"
+)
+
+// A reservation that failed to settle waits settleBackoff before its next
+// attempt, doubling with every failure up to settleMaxBackoff.
+const (
+	settleBackoff    = time.Minute
+	settleMaxBackoff = time.Hour
+)
+
+// SettleResult counts what one SettleExpired batch did.
+type SettleResult struct {
+	Refunded int
+	Used     int
+	Failed   int
+}
+
+func (r SettleResult) Processed() int {
+	return r.Refunded + r.Used
+}
+
+// SettleExpired settles up to batch active reservations whose TTL elapsed.
+// A reservation whose message was sent is marked used; one whose message
+// failed, was rejected, expired or never existed is refunded. Reservations of messages
+// still queued, sending or deferred are left to the worker.
+//
+// Rows are locked with SKIP LOCKED, so every replica can run this at the same
+// time without settling a reservation twice. Each row is settled under its own
+// savepoint so one bad row does not abort the batch; a row that fails is
+// retried with exponential backoff, capped at settleMaxBackoff.
+func (s *Service) SettleExpired(ctx context.Context, batch int) (SettleResult, error) {
+	var result SettleResult
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return result, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `
+        SELECT r.id, COALESCE(m.status, '')
+        FROM reservations r
+        LEFT JOIN messages m ON m.message_id = r.message_id
+        WHERE r.state = 'active'
+          AND r.expires_at < NOW()
+          AND (r.next_attempt_at IS NULL OR r.next_attempt_at <= NOW())
+          AND (m.status IS NULL OR m.status NOT IN ('queued', 'sending', 'deferred'))
+        ORDER BY r.expires_at
+        LIMIT $1
+        FOR UPDATE OF r SKIP LOCKED`, batch)
+	if err != nil {
+		return result, err
+	}
+	type expired struct {
+		id, status string
+	}
+	var batchRows []expired
+	for rows.Next() {
+		var e expired
+		if err := rows.Scan(&e.id, &e.status); err != nil {
+			rows.Close()
+			return result, err
+		}
+		batchRows = append(batchRows, e)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return result, err
+	}
+
+	credits := map[string]int64{}
+	available := map[string]int64{}
+	for _, e := range batchRows {
+		if _, err := tx.ExecContext(ctx, `SAVEPOINT settle`); err != nil {
+			return result, err
+		}
+
+		var err error
+		switch e.status {
+		case "sent", "delivered", "undelivered":
+			_, err = tx.ExecContext(ctx, `
+                UPDATE reservations SET state='used', used=true, settled_at=NOW()
+                WHERE id=$1 AND state='active'`, e.id)
+			if err == nil {
+				result.Used++
+			}
+		default:
+			var userID string
+			var amount, left int64
+			userID, amount, left, err = refund(ctx, tx, e.id, "reservation expired")
+			if err == nil {
+				credits[userID] += amount
+				available[userID] = left
+				result.Refunded++
+			}
+		}
+
+		if err != nil && err != sql.ErrNoRows {
+			logger.Error("Failed to settle expired reservation", zap.String("reservation_id", e.id), zap.Error(err))
+			result.Failed++
+			if _, err := tx.ExecContext(ctx, `ROLLBACK TO SAVEPOINT settle`); err != nil {
+				return result, err
+			}
+			if _, err := tx.ExecContext(ctx, `
+                UPDATE reservations
+                SET settle_attempts = settle_attempts + 1,
+                    next_attempt_at = NOW() + LEAST(
+                        make_interval(secs => $2 * POWER(2, LEAST(settle_attempts, 20))),
+                        make_interval(secs => $3))
+                WHERE id=$1`, e.id, settleBackoff.Seconds(), settleMaxBackoff.Seconds()); err != nil {
+				return result, err
+			}
+			continue
+		}
+		if _, err := tx.ExecContext(ctx, `RELEASE SAVEPOINT settle`); err != nil {
+			return result, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return SettleResult{Failed: len(batchRows)}, err
+	}
+	for userID, amount := range credits {
+		s.creditCounter(ctx, userID, amount)
+		alerts.Evaluate(userID, available[userID])
+	}
+	return result, nil
+}
@@ -1,7 +1,11 @@
 package reservation
 
 import (
+	"context"
 	"database/sql"
+
+	"arvan-sms-gateway/internal/alerts"
+	"arvan-sms-gateway/internal/db"
 )
 
 type PostgresReserver struct {
@@ -19,22 +23,16 @@ func (r *PostgresReserver) Reserve(userID string, tokens int) (bool, error) {
 	}
 	defer tx.Rollback()
 
-	var balance int
-	err = tx.QueryRow(`SELECT balance FROM users WHERE id=$1 FOR UPDATE`, userID).Scan(&balance)
-	if err != nil {
+	w, ok, err := db.Debit(context.Background(), tx, userID, int64(tokens))
+	if err != nil || !ok {
 		return false, err
 	}
 
-	if balance < tokens {
+	if err := tx.Commit(); err != nil {
 		return false, nil
 	}
-
-	_, err = tx.Exec(`UPDATE users SET balance=balance-$1 WHERE id=$2`, tokens, userID)
-	if err != nil {
-		return false, err
-	}
-
-	return tx.Commit() == nil, nil
+	alerts.Evaluate(userID, w.Available())
+	return true, nil
 }
 
 func (r *PostgresReserver) Commit(userID string, tokens int) error {
@@ -43,6 +41,11 @@ func (r *PostgresReserver) Commit(userID string, tokens int) error {
 }
 
 func (r *PostgresReserver) Rollback(userID string, tokens int) error {
-	_, err := r.db.Exec(`UPDATE users SET balance=balance+$1 WHERE id=$2`, tokens, userID)
-	return err
+	var available int64
+	err := r.db.QueryRow(`UPDATE users SET balance=balance+$1 WHERE id=$2 RETURNING balance + credit_limit`, tokens, userID).Scan(&available)
+	if err != nil {
+		return err
+	}
+	alerts.Evaluate(userID, available)
+	return nil
 }
@@ -0,0 +1,229 @@
+package reservation
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"arvan-sms-gateway/internal/logger"
+	"arvan-sms-gateway/internal/metrics"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// reconcileLockID is the Postgres advisory lock that keeps concurrent
+// reconcilers (one per gateway replica) from working on the same run.
+const reconcileLockID = 0x5741_4c4c_4554 // "WALLET"
+
+// setIfUnchanged overwrites the counter only if nobody touched it since it was
+// read, so a reservation racing with the reconciler is never lost.
+var setIfUnchanged = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+    redis.call('SET', KEYS[1], ARGV[2], 'KEEPTTL')
+    return 1
+end
+return 0`)
+
+// Drift is one user whose Redis wallet counter disagrees with Postgres.
+type Drift struct {
+	UserID    string
+	Redis     int64
+	Postgres  int64
+	Corrected bool
+}
+
+type ReconcileReport struct {
+	Checked   int
+	Drifted   []Drift
+	Negative  []string // users whose Postgres balance is below -credit_limit
+	Orphaned  []string // counters for users that do not exist in Postgres
+	Corrected int
+	Skipped   bool // another replica held the lock
+}
+
+// Reconcile compares every cached wallet counter with Postgres. users.balance
+// is debited when a reservation is taken, so it already excludes outstanding
+// reservations; the counter should hold it plus the user's credit limit.
+//
+// A mismatch is only acted on if it is still there, unchanged on both sides,
+// after the grace period; in-flight reservations touch Redis first and
+// Postgres a few milliseconds later, and must not be mistaken for drift.
+// With dryRun set, drift is logged and counted but nothing is written.
+func (s *Service) Reconcile(ctx context.Context, dryRun bool, batch int64, grace time.Duration) (*ReconcileReport, error) {
+	report := &ReconcileReport{}
+
+	conn, err := s.db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	var locked bool
+	if err := conn.QueryRowContext(ctx, `SELECT pg_try_advisory_lock($1)`, reconcileLockID).Scan(&locked); err != nil {
+		return nil, err
+	}
+	if !locked {
+		report.Skipped = true
+		return report, nil
+	}
+	defer conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, reconcileLockID)
+
+	var cursor uint64
+	for {
+		keys, next, err := s.rdb.Scan(ctx, cursor, s.bucket+":*", batch).Result()
+		if err != nil {
+			return report, err
+		}
+		if len(keys) > 0 {
+			if err := s.reconcileKeys(ctx, keys, dryRun, grace, report); err != nil {
+				return report, err
+			}
+		}
+		cursor = next
+		if cursor == 0 {
+			break
+		}
+	}
+
+	metrics.WalletDriftUsers.Set(float64(len(report.Drifted)))
+	metrics.WalletNegativeBalances.Set(float64(len(report.Negative)))
+	var tokens int64
+	for _, d := range report.Drifted {
+		if d.Redis > d.Postgres {
+			tokens += d.Redis - d.Postgres
+		} else {
+			tokens += d.Postgres - d.Redis
+		}
+	}
+	metrics.WalletDriftTokens.Set(float64(tokens))
+	return report, nil
+}
+
+type walletSnapshot struct {
+	redis    map[string]string // raw counter values keyed by user ID
+	postgres map[string]int64  // available balance: balance + credit_limit
+}
+
+func (s *Service) snapshot(ctx context.Context, userIDs []string) (*walletSnapshot, error) {
+	snap := &walletSnapshot{redis: map[string]string{}, postgres: map[string]int64{}}
+
+	keys := make([]string, len(userIDs))
+	for i, id := range userIDs {
+		keys[i] = s.bucket + ":" + id
+	}
+	vals, err := s.rdb.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, err
+	}
+	for i, v := range vals {
+		if str, ok := v.(string); ok {
+			snap.redis[userIDs[i]] = str
+		}
+	}
+
+	rows, err := s.db.QueryContext(ctx, `SELECT id, balance + credit_limit FROM users WHERE id = ANY($1::uuid[])`, pq.Array(userIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id string
+		var balance int64
+		if err := rows.Scan(&id, &balance); err != nil {
+			return nil, err
+		}
+		snap.postgres[id] = balance
+	}
+	return snap, rows.Err()
+}
+
+func (s *Service) reconcileKeys(ctx context.Context, keys []string, dryRun bool, grace time.Duration, report *ReconcileReport) error {
+	var userIDs []string
+	for _, k := range keys {
+		id := strings.TrimPrefix(k, s.bucket+":")
+		if _, err := uuid.Parse(id); err != nil {
+			report.Orphaned = append(report.Orphaned, id)
+			continue
+		}
+		userIDs = append(userIDs, id)
+	}
+	if len(userIDs) == 0 {
+		return nil
+	}
+
+	first, err := s.snapshot(ctx, userIDs)
+	if err != nil {
+		return err
+	}
+
+	var suspects []string
+	for _, id := range userIDs {
+		raw, cached := first.redis[id]
+		if !cached {
+			continue // expired between SCAN and MGET
+		}
+		report.Checked++
+
+		available, exists := first.postgres[id]
+		if !exists {
+			report.Orphaned = append(report.Orphaned, id)
+			if !dryRun {
+				if err := s.rdb.Del(ctx, s.bucket+":"+id).Err(); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if available < 0 {
+			report.Negative = append(report.Negative, id)
+			logger.Warn("Wallet balance in Postgres is past its credit limit",
+				zap.String("user_id", id), zap.Int64("available", available))
+		}
+		if raw != strconv.FormatInt(available, 10) {
+			suspects = append(suspects, id)
+		}
+	}
+
+	if len(suspects) > 0 {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(grace):
+		}
+		second, err := s.snapshot(ctx, suspects)
+		if err != nil {
+			return err
+		}
+		for _, id := range suspects {
+			raw := first.redis[id]
+			if second.redis[id] != raw || second.postgres[id] != first.postgres[id] {
+				continue // still moving, look again on the next run
+			}
+			cached, _ := strconv.ParseInt(raw, 10, 64)
+			d := Drift{UserID: id, Redis: cached, Postgres: first.postgres[id]}
+			if !dryRun {
+				n, err := setIfUnchanged.Run(ctx, s.rdb, []string{s.bucket + ":" + id}, raw, d.Postgres).Int()
+				if err != nil {
+					return err
+				}
+				d.Corrected = n == 1
+			}
+			if d.Corrected {
+				report.Corrected++
+				metrics.WalletReconcileCorrections.Inc()
+			}
+			report.Drifted = append(report.Drifted, d)
+			logger.Warn("Wallet drift detected",
+				zap.String("user_id", id),
+				zap.Int64("redis", d.Redis),
+				zap.Int64("postgres", d.Postgres),
+				zap.Bool("dry_run", dryRun),
+				zap.Bool("corrected", d.Corrected))
+		}
+	}
+
+	return nil
+}
@@ -22,6 +22,8 @@ func NewRedisReserver(addr string) *RedisReserver {
 	}
 }
 
+// Reserve debits the cached available balance (balance + credit limit), so
+// going below zero means the debit would pass the account's credit limit.
 func (r *RedisReserver) Reserve(userID string, tokens int) (bool, error) {
 	ctx := context.Background()
 	key := r.bucket + ":" + userID
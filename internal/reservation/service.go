@@ -3,109 +3,278 @@ package reservation
 import (
 	"context"
 	"database/sql"
+	"fmt"
 	"time"
 
+	"arvan-sms-gateway/internal/alerts"
+	"arvan-sms-gateway/internal/breaker"
+	"arvan-sms-gateway/internal/db"
+	"arvan-sms-gateway/internal/logger"
+	"arvan-sms-gateway/internal/metrics"
+	"arvan-sms-gateway/internal/tracing"
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
 )
 
+// incrIfExists credits the wallet counter only while it is cached; a missing
+// key is rebuilt from Postgres by the next Reserve.
+var incrIfExists = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 1 then
+    return redis.call('INCRBY', KEYS[1], ARGV[1])
+end
+return nil`)
+
 type Service struct {
 	db     *sql.DB
 	rdb    *redis.Client
 	bucket string
 	ttl    time.Duration
+	// reservationTTL is how long a reservation waits for its worker before
+	// the expiry job settles it.
+	reservationTTL time.Duration
 }
 
-func NewService(db *sql.DB, redisAddr string) *Service {
-	rdb := redis.NewClient(&redis.Options{Addr: redisAddr})
+func NewService(db *sql.DB, redisAddr string, reservationTTL time.Duration) *Service {
+	rdb := redis.NewClient(&redis.Options{Addr: redisAddr, ContextTimeoutEnabled: true})
+	tracing.InstrumentRedis(rdb)
 	return &Service{
-		db:     db,
-		rdb:    rdb,
-		bucket: "wallet_tokens",
-		ttl:    5 * time.Minute,
+		db:             db,
+		rdb:            rdb,
+		bucket:         "wallet_tokens",
+		ttl:            5 * time.Minute,
+		reservationTTL: reservationTTL,
 	}
 }
 
-func (s *Service) Reserve(userID string, tokens int64) (string, bool, error) {
-	ctx := context.Background()
-	key := s.bucket + ":" + userID
-
-	val, err := s.rdb.DecrBy(ctx, key, tokens).Result()
-	if err != nil && err != redis.Nil {
-		return "", false, err
+// Ping checks both stores a reservation needs.
+func (s *Service) Ping(ctx context.Context) error {
+	if err := s.rdb.Ping(ctx).Err(); err != nil {
+		return fmt.Errorf("redis: %w", err)
+	}
+	if err := s.db.PingContext(ctx); err != nil {
+		return fmt.Errorf("postgres: %w", err)
 	}
+	return nil
+}
 
-	if val >= 0 {
-		tx, err := s.db.Begin()
-		if err != nil {
-			return "", false, err
-		}
-		defer tx.Rollback()
+// withRedis runs fn through the Redis breaker, with its timeout.
+func (s *Service) withRedis(ctx context.Context, fn func(ctx context.Context) error) error {
+	return breaker.Redis().Do(ctx, fn)
+}
 
-		_, err = tx.Exec(`UPDATE users SET balance=balance-$1 WHERE id=$2`, tokens, userID)
-		if err != nil {
-			return "", false, err
-		}
+// Reserve debits tokens for one message and returns the reservation ID that
+// the worker later settles with MarkUsed or Refund. The Redis counter is a
+// fast pre-check only; Postgres has the final say on every debit, so while
+// Redis is slow or its breaker is open the reservation is made in Postgres
+// alone.
+func (s *Service) Reserve(ctx context.Context, userID, messageID string, tokens int64) (string, bool, error) {
+	key := s.bucket + ":" + userID
 
-		resID := uuid.New().String()
-		_, err = tx.Exec(`INSERT INTO reservations (id, user_id, amount, expires_at) VALUES ($1, $2, $3, NOW() + interval '5 minutes')`,
-			resID, userID, tokens)
-		if err != nil {
-			return "", false, err
-		}
+	var val int64
+	err := s.withRedis(ctx, func(ctx context.Context) error {
+		var err error
+		val, err = s.rdb.DecrBy(ctx, key, tokens).Result()
+		return err
+	})
+	redisUp := err == nil
+	if !redisUp {
+		logger.WarnCtx(ctx, "Redis unavailable, reserving from Postgres only", zap.Error(err))
+		val = -1
+	}
 
-		if err := tx.Commit(); err != nil {
-			return "", false, err
+	resID, available, ok, err := s.reserve(ctx, userID, messageID, tokens)
+	if err != nil {
+		if val >= 0 {
+			err := s.withRedis(ctx, func(ctx context.Context) error {
+				return s.rdb.IncrBy(ctx, key, tokens).Err()
+			})
+			if err != nil {
+				logger.WarnCtx(ctx, "Failed to restore Redis wallet counter", zap.String("user_id", userID), zap.Error(err))
+			}
 		}
-
+		return "", false, err
+	}
+	if ok {
+		alerts.Evaluate(userID, available)
+	}
+	if !redisUp {
+		metrics.ReservationPath.WithLabelValues("fallback").Inc()
+		return resID, ok, nil
+	}
+	if val >= 0 && ok {
+		metrics.ReservationPath.WithLabelValues("fast").Inc()
 		return resID, true, nil
 	}
+	metrics.ReservationPath.WithLabelValues("slow").Inc()
+
+	if val >= 0 {
+		logger.WarnCtx(ctx, "Redis wallet counter ahead of Postgres balance",
+			zap.String("user_id", userID),
+			zap.Int64("redis", val+tokens),
+			zap.Int64("postgres", available))
+	}
+	// slow path or disagreement: resync the counter from Postgres
+	_ = s.withRedis(ctx, func(ctx context.Context) error {
+		return s.rdb.Set(ctx, key, available, s.ttl).Err()
+	})
 
-	tx, err := s.db.Begin()
+	return resID, ok, nil
+}
+
+// reserve debits users.balance and records the reservation in one
+// transaction. It returns the available balance (balance + credit limit)
+// after the debit, or the current one when the debit was refused.
+func (s *Service) reserve(ctx context.Context, userID, messageID string, tokens int64) (string, int64, bool, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
-		return "", false, err
+		return "", 0, false, err
 	}
 	defer tx.Rollback()
 
-	var balance int64
-	err = tx.QueryRow(`SELECT balance FROM users WHERE id=$1 FOR UPDATE`, userID).Scan(&balance)
+	w, ok, err := db.Debit(ctx, tx, userID, tokens)
 	if err != nil {
-		return "", false, err
+		return "", 0, false, err
 	}
-	if balance < tokens {
-		return "", false, nil
+	if !ok {
+		return "", w.Available(), false, nil
 	}
 
-	_, err = tx.Exec(`UPDATE users SET balance=balance-$1 WHERE id=$2`, tokens, userID)
+	resID := uuid.New().String()
+	_, err = tx.ExecContext(ctx, `INSERT INTO reservations (id, user_id, message_id, amount, expires_at) VALUES ($1, $2, $3, $4, NOW() + make_interval(secs => $5))`,
+		resID, userID, messageID, tokens, s.reservationTTL.Seconds())
 	if err != nil {
-		return "", false, err
+		return "", 0, false, err
 	}
 
-	resID := uuid.New().String()
-	_, err = tx.Exec(`INSERT INTO reservations (id, user_id, amount, expires_at) VALUES ($1, $2, $3, NOW() + interval '5 minutes')`,
-		resID, userID, tokens)
+	if err := tx.Commit(); err != nil {
+		return "", 0, false, err
+	}
+	return resID, w.Available(), true, nil
+}
+
+// MarkUsed settles a reservation whose message was sent. It is idempotent and
+// reports whether this call settled it.
+func (s *Service) MarkUsed(ctx context.Context, reservationID string) (bool, error) {
+	res, err := s.db.ExecContext(ctx, `
+        UPDATE reservations SET state='used', used=true, settled_at=NOW()
+        WHERE id=$1 AND state='active'`, reservationID)
 	if err != nil {
-		return "", false, err
+		return false, err
 	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+// Refund returns the reserved tokens to users.balance and to the Redis
+// wallet counter, and writes a refund ledger entry. Only an active
+// reservation can be refunded, so calling it again for the same reservation
+// is a no-op that returns false.
+func (s *Service) Refund(ctx context.Context, reservationID, reason string) (bool, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
 
+	userID, amount, available, err := refund(ctx, tx, reservationID, reason)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
 	if err := tx.Commit(); err != nil {
-		return "", false, err
+		return false, err
 	}
 
-	_ = s.rdb.Set(ctx, key, balance-tokens, s.ttl).Err()
+	s.creditCounter(ctx, userID, amount)
+	alerts.Evaluate(userID, available)
+	return true, nil
+}
+
+// refund returns the user, the refunded amount and the available balance
+// after it.
+func refund(ctx context.Context, tx *sql.Tx, reservationID, reason string) (string, int64, int64, error) {
+	var userID string
+	var amount int64
+	var messageID sql.NullString
+	err := tx.QueryRowContext(ctx, `
+        UPDATE reservations SET state='refunded', settled_at=NOW()
+        WHERE id=$1 AND state='active'
+        RETURNING user_id, amount, message_id`, reservationID).Scan(&userID, &amount, &messageID)
+	if err != nil {
+		return "", 0, 0, err
+	}
 
-	return resID, true, nil
+	var available int64
+	err = tx.QueryRowContext(ctx, `UPDATE users SET balance=balance+$1 WHERE id=$2 RETURNING balance + credit_limit`, amount, userID).Scan(&available)
+	if err != nil {
+		return "", 0, 0, err
+	}
+	_, err = tx.ExecContext(ctx, `
+        INSERT INTO balance_ledger (user_id, amount, kind, reservation_id, message_id, reason)
+        VALUES ($1, $2, 'refund', $3, $4, $5)`, userID, amount, reservationID, messageID, reason)
+	if err != nil {
+		return "", 0, 0, err
+	}
+	return userID, amount, available, nil
 }
 
-func (s *Service) Rollback(userID string, tokens int64) error {
-	ctx := context.Background()
-	key := s.bucket + ":" + userID
-	_ = s.rdb.IncrBy(ctx, key, tokens).Err()
-	_, err := s.db.Exec(`UPDATE users SET balance=balance+$1 WHERE id=$2`, tokens, userID)
-	return err
+// creditCounter mirrors a committed refund, or a chunk debit when amount is
+// negative, in the Redis wallet counter. A failure only leaves drift behind,
+// which the wallet reconciler repairs.
+func (s *Service) creditCounter(ctx context.Context, userID string, amount int64) {
+	err := s.withRedis(ctx, func(ctx context.Context) error {
+		err := incrIfExists.Run(ctx, s.rdb, []string{s.bucket + ":" + userID}, amount).Err()
+		if err == redis.Nil {
+			return nil
+		}
+		return err
+	})
+	if err != nil {
+		logger.WarnCtx(ctx, "Balance change not applied to Redis wallet counter",
+			zap.String("user_id", userID),
+			zap.Int64("amount", amount),
+			zap.Error(err))
+	}
 }
 
-func (s *Service) MarkUsed(reservationID string) error {
-	_, err := s.db.Exec(`UPDATE reservations SET used=true WHERE id=$1`, reservationID)
-	return err
+// ReserveChunk debits tokens for every message in one transaction and returns
+// one reservation per message, in order, so each is settled by the worker like
+// a single reservation. Either the whole chunk is reserved or none of it.
+func (s *Service) ReserveChunk(ctx context.Context, userID string, messageIDs []string, tokens int64) ([]string, bool, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, false, err
+	}
+	defer tx.Rollback()
+
+	total := tokens * int64(len(messageIDs))
+	w, ok, err := db.Debit(ctx, tx, userID, total)
+	if err != nil || !ok {
+		return nil, false, err
+	}
+
+	resIDs := make([]string, len(messageIDs))
+	for i := range resIDs {
+		resIDs[i] = uuid.New().String()
+	}
+	_, err = tx.ExecContext(ctx, `
+        INSERT INTO reservations (id, user_id, message_id, amount, expires_at)
+        SELECT r.id, $2, r.message_id, $4, NOW() + make_interval(secs => $5)
+        FROM unnest($1::uuid[], $3::uuid[]) AS r(id, message_id)`,
+		pq.Array(resIDs), userID, pq.Array(messageIDs), tokens, s.reservationTTL.Seconds())
+	if err != nil {
+		return nil, false, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, false, err
+	}
+
+	metrics.ReservationPath.WithLabelValues("chunk").Inc()
+	s.creditCounter(ctx, userID, -total)
+	alerts.Evaluate(userID, w.Available())
+	return resIDs, true, nil
 }
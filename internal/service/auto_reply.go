@@ -0,0 +1,130 @@
+package service
+
+import (
+	"arvan-sms-gateway/internal/db"
+	"arvan-sms-gateway/internal/logger"
+	"arvan-sms-gateway/internal/metrics"
+	"arvan-sms-gateway/internal/models"
+	"context"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// matchOrder ranks match types; the most specific matching rule answers.
+var matchOrder = map[models.MatchType]int{
+	models.MatchExact:    0,
+	models.MatchPrefix:   1,
+	models.MatchContains: 2,
+	models.MatchAny:      3,
+}
+
+// MatchAutoReply returns the rule answering text: an exact match before a
+// prefix match, before a contains match, before a rule for any text, and
+// the oldest rule among equals. It returns nil when no rule matches.
+func MatchAutoReply(rules []models.AutoReplyRule, text string) *models.AutoReplyRule {
+	upper := strings.ToUpper(strings.Join(strings.Fields(text), " "))
+	var best *models.AutoReplyRule
+	for i := range rules {
+		r := &rules[i]
+		var ok bool
+		switch r.MatchType {
+		case models.MatchExact:
+			ok = strings.Trim(upper, ".,!?;:") == r.Keyword
+		case models.MatchPrefix:
+			ok = InboundKeyword(text) == r.Keyword
+		case models.MatchContains:
+			ok = strings.Contains(upper, r.Keyword)
+		case models.MatchAny:
+			ok = true
+		}
+		if ok && (best == nil || matchOrder[r.MatchType] < matchOrder[best.MatchType] ||
+			matchOrder[r.MatchType] == matchOrder[best.MatchType] && r.ID < best.ID) {
+			best = r
+		}
+	}
+	return best
+}
+
+// autoReply runs the rule of the routed message m's user that matches its
+// text, if any: the action is always applied, the reply is sent unless the
+// rule answered the sender within its cooldown. Replies go through
+// ProcessSMSRequest, so they are charged and tracked like any message. Errors
+// are logged; the inbound message is stored either way.
+func autoReply(ctx context.Context, m *models.InboundMessage) {
+	rules, err := db.ListAutoReplyRules(ctx, m.UserID, m.To)
+	if err != nil {
+		logger.ErrorCtx(ctx, "Failed to load auto-reply rules", zap.String("inbound_id", m.ID), zap.Error(err))
+		return
+	}
+	rule := MatchAutoReply(rules, m.Text)
+	if rule == nil {
+		return
+	}
+	log := []zap.Field{zap.String("inbound_id", m.ID), zap.Int64("rule_id", rule.ID)}
+
+	switch rule.Action {
+	case models.ActionOptOut:
+		err = db.AddOptOut(ctx, m.UserID, m.From, rule.ID)
+	case models.ActionOptIn:
+		err = db.RemoveOptOut(ctx, m.UserID, m.From)
+	case models.ActionTag:
+		err = db.AddContactTag(ctx, m.UserID, m.From, rule.Tag)
+	}
+	if err != nil {
+		logger.ErrorCtx(ctx, "Auto-reply action failed", append(log, zap.String("action", string(rule.Action)), zap.Error(err))...)
+	}
+
+	replyID := ""
+	if rule.Reply != "" {
+		replyID = sendAutoReply(ctx, rule, m, log)
+	} else {
+		metrics.AutoReplies.WithLabelValues("action_only").Inc()
+	}
+
+	if err := db.SetInboundReply(ctx, m.ID, rule.ID, replyID); err != nil {
+		logger.ErrorCtx(ctx, "Failed to link auto-reply", append(log, zap.Error(err))...)
+	}
+	m.ReplyMessageID = replyID
+}
+
+// sendAutoReply returns the id of the reply queued, or "" when none was.
+func sendAutoReply(ctx context.Context, rule *models.AutoReplyRule, m *models.InboundMessage, log []zap.Field) string {
+	if rule.CooldownSeconds > 0 {
+		ok, err := db.TakeAutoReplyCooldown(ctx, rule.ID, m.From, time.Duration(rule.CooldownSeconds)*time.Second)
+		if err != nil {
+			logger.ErrorCtx(ctx, "Auto-reply cooldown check failed", append(log, zap.Error(err))...)
+			metrics.AutoReplies.WithLabelValues("failed").Inc()
+			return ""
+		}
+		if !ok {
+			metrics.AutoReplies.WithLabelValues("cooldown").Inc()
+			return ""
+		}
+	}
+
+	req := models.SMSRequest{
+		UserID:      rule.UserID,
+		PhoneNumber: m.From,
+		Message: renderTemplate(rule.Reply, map[string]string{
+			"from": m.From, "to": m.To, "keyword": m.Keyword,
+		}),
+		MessageID: uuid.New().String(),
+		Priority:  models.PriorityTransactional,
+	}
+	result, err := ProcessSMSRequest(ctx, req, serviceConfig)
+	if err != nil || result.StatusCode != http.StatusOK {
+		metrics.AutoReplies.WithLabelValues("failed").Inc()
+		logger.WarnCtx(ctx, "Auto-reply not sent",
+			append(log, zap.String("message_id", req.MessageID), zap.String("reason", result.Message), zap.Error(err))...)
+		if result.StatusCode == http.StatusBadRequest || result.StatusCode == http.StatusForbidden {
+			// rejected, e.g. for balance; the message records why
+			return req.MessageID
+		}
+		return ""
+	}
+	metrics.AutoReplies.WithLabelValues("sent").Inc()
+	return req.MessageID
+}
@@ -0,0 +1,314 @@
+package service
+
+import (
+	"arvan-sms-gateway/internal/db"
+	"arvan-sms-gateway/internal/logger"
+	"arvan-sms-gateway/internal/metrics"
+	"arvan-sms-gateway/internal/models"
+	"arvan-sms-gateway/internal/quiethours"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const campaignEndpoint = "campaign"
+
+// maxImportErrors caps the row errors reported back for one upload.
+const maxImportErrors = 20
+
+var (
+	ErrInvalidCSV        = errors.New("invalid CSV")
+	ErrTooManyRecipients = errors.New("too many recipients")
+
+	placeholder = regexp.MustCompile(`\{([A-Za-z0-9_]+)\}`)
+)
+
+// Placeholders returns the {column} names referenced by a campaign body.
+func Placeholders(body string) []string {
+	var names []string
+	for _, m := range placeholder.FindAllStringSubmatch(body, -1) {
+		names = append(names, m[1])
+	}
+	return names
+}
+
+func renderTemplate(body string, vars map[string]string) string {
+	return placeholder.ReplaceAllStringFunc(body, func(m string) string {
+		return vars[m[1:len(m)-1]]
+	})
+}
+
+func CreateCampaign(ctx context.Context, req models.CampaignCreateRequest) (*models.Campaign, error) {
+	c := &models.Campaign{
+		ID:            uuid.New().String(),
+		UserID:        req.UserID,
+		Name:          req.Name,
+		Body:          req.Body,
+		RatePerSecond: req.RatePerSecond,
+		ShortenLinks:  req.ShortenLinks,
+	}
+	if err := db.CreateCampaign(ctx, c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+type ImportResult struct {
+	Added      int      `json:"added"`
+	Duplicates int      `json:"duplicates"`
+	Rejected   int      `json:"rejected"`
+	Errors     []string `json:"errors,omitempty"`
+}
+
+// ImportRecipients reads a CSV with a header row and adds its rows to a draft
+// campaign. Every placeholder of the body must be a column; the other columns
+// are kept as variables. Invalid rows are rejected and reported, the valid
+// ones are stored.
+func ImportRecipients(ctx context.Context, c *models.Campaign, r io.Reader, maxRecipients int) (*ImportResult, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+
+	header, err := cr.Read()
+	if err == io.EOF {
+		return nil, fmt.Errorf("%w: file is empty", ErrInvalidCSV)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCSV, err)
+	}
+	phoneCol := -1
+	columns := map[string]bool{}
+	for i, name := range header {
+		name = strings.TrimSpace(strings.TrimPrefix(name, "\ufeff"))
+		header[i] = name
+		columns[name] = true
+		switch strings.ToLower(name) {
+		case "phone_number", "phone", "mobile":
+			if phoneCol < 0 {
+				phoneCol = i
+			}
+		}
+	}
+	if phoneCol < 0 {
+		return nil, fmt.Errorf("%w: header must have a phone_number, phone or mobile column", ErrInvalidCSV)
+	}
+	for _, name := range Placeholders(c.Body) {
+		if !columns[name] {
+			return nil, fmt.Errorf("%w: body uses {%s} but there is no %q column", ErrInvalidCSV, name, name)
+		}
+	}
+
+	result := &ImportResult{}
+	reject := func(line int, format string, args ...any) {
+		result.Rejected++
+		if len(result.Errors) < maxImportErrors {
+			result.Errors = append(result.Errors, fmt.Sprintf("line %d: ", line)+fmt.Sprintf(format, args...))
+		}
+	}
+
+	var recipients []models.CampaignRecipient
+	seen := map[string]bool{}
+	for line := 2; ; line++ {
+		row, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidCSV, err)
+		}
+		if len(recipients)+result.Duplicates+result.Rejected >= maxRecipients {
+			return nil, fmt.Errorf("%w: at most %d rows per upload", ErrTooManyRecipients, maxRecipients)
+		}
+		if len(row) != len(header) {
+			reject(line, "expected %d fields, got %d", len(header), len(row))
+			continue
+		}
+
+		vars := make(map[string]string, len(row))
+		for i, v := range row {
+			vars[header[i]] = strings.TrimSpace(v)
+		}
+		phone := vars[header[phoneCol]]
+		if len(phone) < 10 {
+			reject(line, "invalid phone number %q", phone)
+			continue
+		}
+		text := renderTemplate(c.Body, vars)
+		if strings.TrimSpace(text) == "" || len(text) > 500 {
+			reject(line, "message must be 1 to 500 characters, got %d", len(text))
+			continue
+		}
+		if seen[phone] {
+			result.Duplicates++
+			continue
+		}
+		seen[phone] = true
+		recipients = append(recipients, models.CampaignRecipient{
+			PhoneNumber: phone,
+			Variables:   vars,
+			MessageID:   uuid.New().String(),
+		})
+	}
+
+	added, err := db.AddCampaignRecipients(ctx, c.ID, recipients)
+	if err != nil {
+		return nil, err
+	}
+	result.Added = added
+	// phone numbers uploaded earlier to the same campaign
+	result.Duplicates += len(recipients) - added
+	return result, nil
+}
+
+type CampaignEstimate struct {
+	Recipients int64 `json:"recipients"`
+	Cost       int64 `json:"cost"`
+	Available  int64 `json:"available"`
+	Sufficient bool  `json:"sufficient"`
+}
+
+// EstimateCampaign prices the recipients not dispatched yet against the
+// user's available balance.
+func EstimateCampaign(ctx context.Context, c *models.Campaign) (*CampaignEstimate, error) {
+	_, pending, err := db.CountCampaignRecipients(ctx, c.ID)
+	if err != nil {
+		return nil, err
+	}
+	wallet, err := db.GetWallet(ctx, c.UserID)
+	if err != nil {
+		return nil, err
+	}
+	cost := pending * messageCost
+	return &CampaignEstimate{
+		Recipients: pending,
+		Cost:       cost,
+		Available:  wallet.Available(),
+		Sufficient: cost <= wallet.Available(),
+	}, nil
+}
+
+// CanRunCampaigns reports whether the user may send marketing messages.
+func CanRunCampaigns(ctx context.Context, userID string) (bool, error) {
+	user, err := GetUserData(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	return user.CanSend(models.PriorityMarketing), nil
+}
+
+// DispatchCampaignChunk reserves balance for a chunk of recipients in one
+// debit and queues their messages on the marketing topic. It is the
+// db.CampaignDispatch used by the campaign dispatcher.
+func DispatchCampaignChunk(ctx context.Context, c *models.Campaign, recipients []models.CampaignRecipient) (int, string) {
+	ctx = logger.WithUserID(ctx, c.UserID)
+	log := []zap.Field{zap.String("campaign_id", c.ID)}
+
+	// Hold the campaign during quiet hours rather than deferring every
+	// recipient to the same minute; recipients abroad whose own quiet hours
+	// differ are deferred one by one when queued.
+	if !quiethours.Current().DefaultUntil(time.Now()).IsZero() {
+		return 0, ""
+	}
+
+	allowed, err := CanRunCampaigns(ctx, c.UserID)
+	if err != nil {
+		logger.ErrorCtx(ctx, "Failed to fetch campaign user", append(log, zap.Error(err))...)
+		return 0, ""
+	}
+	if !allowed {
+		return 0, "priority marketing not allowed for this user"
+	}
+	if c.ShortenLinks && serviceConfig.ShortLinkBaseURL == "" {
+		return 0, ErrLinksDisabled.Error()
+	}
+
+	ids := make([]string, len(recipients))
+	phones := make([]string, len(recipients))
+	for i, r := range recipients {
+		ids[i] = r.MessageID
+		phones[i] = NormalizeNumber(r.PhoneNumber)
+	}
+	optedOut, err := db.OptedOut(ctx, c.UserID, phones)
+	if err != nil {
+		logger.ErrorCtx(ctx, "Failed to check campaign opt-outs", append(log, zap.Error(err))...)
+		return 0, ""
+	}
+
+	// Send what the user's quotas still allow and hold the rest until they
+	// reset; messages not queued in the end are given back.
+	take, _, err := takeQuota(ctx, c.UserID, models.PriorityMarketing, int64(len(recipients)), true)
+	if err != nil || take.n == 0 {
+		return 0, ""
+	}
+	recipients, ids, phones = recipients[:take.n], ids[:take.n], phones[:take.n]
+	var queued int64
+	defer func() { releaseQuota(ctx, take, take.n-queued) }()
+	resIDs, ok, err := reserverService.ReserveChunk(ctx, c.UserID, ids, messageCost)
+	if err != nil {
+		logger.ErrorCtx(ctx, "Campaign reservation error", append(log, zap.Error(err))...)
+		return 0, ""
+	}
+	if !ok {
+		return 0, "insufficient balance"
+	}
+
+	topic := models.PriorityMarketing.Topic(serviceConfig.KafkaTopicNormal)
+	for i, r := range recipients {
+		req := models.SMSRequest{
+			UserID:       c.UserID,
+			PhoneNumber:  r.PhoneNumber,
+			Message:      renderTemplate(c.Body, r.Variables),
+			MessageID:    r.MessageID,
+			Priority:     models.PriorityMarketing,
+			CampaignID:   c.ID,
+			ShortenLinks: c.ShortenLinks,
+		}
+		applyValidity(&req, serviceConfig)
+		mctx := logger.WithMessageID(ctx, r.MessageID)
+		err := applyShortLinks(&req, serviceConfig.ShortLinkBaseURL)
+		if err == nil {
+			err = db.InsertMessage(mctx, req, models.StatusQueued)
+		}
+		if err != nil {
+			refundChunk(mctx, resIDs[i:i+1])
+			if db.IsDuplicate(err) {
+				// queued by a dispatch whose progress was not saved
+				metrics.CampaignMessages.WithLabelValues("duplicate").Inc()
+				continue
+			}
+			logger.ErrorCtx(mctx, "Failed to insert campaign message", append(log, zap.Error(err))...)
+			refundChunk(mctx, resIDs[i+1:])
+			return i, ""
+		}
+		if optedOut[phones[i]] {
+			refundChunk(mctx, resIDs[i:i+1])
+			setStatus(mctx, r.MessageID, models.StatusRejected, "recipient opted out")
+			metrics.CampaignMessages.WithLabelValues("opted_out").Inc()
+			continue
+		}
+		result, _ := enqueue(mctx, topic, models.QueuedSMS{SMSRequest: req, ReservationID: resIDs[i]}, campaignEndpoint)
+		if result.StatusCode == http.StatusOK {
+			queued++
+			metrics.CampaignMessages.WithLabelValues("queued").Inc()
+		} else {
+			metrics.CampaignMessages.WithLabelValues("failed").Inc()
+		}
+	}
+	return len(recipients), ""
+}
+
+func refundChunk(ctx context.Context, resIDs []string) {
+	for _, id := range resIDs {
+		if _, err := reserverService.Refund(ctx, id, "campaign message not queued"); err != nil {
+			logger.ErrorCtx(ctx, "Reservation refund failed", zap.String("reservation_id", id), zap.Error(err))
+		}
+	}
+}
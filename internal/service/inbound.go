@@ -0,0 +1,86 @@
+package service
+
+import (
+	"arvan-sms-gateway/internal/db"
+	"arvan-sms-gateway/internal/logger"
+	"arvan-sms-gateway/internal/metrics"
+	"arvan-sms-gateway/internal/models"
+	"context"
+	"database/sql"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"strings"
+	"time"
+)
+
+// NormalizeNumber reduces a phone number or short code to its digits, so
+// "+98 300 0123" and "00983000123" compare equal.
+func NormalizeNumber(number string) string {
+	number = strings.NewReplacer(" ", "", "-", "", "(", "", ")", "").Replace(strings.TrimSpace(number))
+	number = strings.TrimPrefix(number, "+")
+	return strings.TrimPrefix(number, "00")
+}
+
+// InboundKeyword returns the first word of an inbound text, upper-cased.
+func InboundKeyword(text string) string {
+	fields := strings.Fields(text)
+	if len(fields) == 0 {
+		return ""
+	}
+	return strings.ToUpper(strings.Trim(fields[0], ".,!?;:"))
+}
+
+// ReceiveInbound stores a mobile-originated message reported by provider,
+// routes it to a user by destination number and keyword and runs the user's
+// auto-reply rules on it. It serves the provider ingestion endpoint and is
+// what an SMPP deliver_sm handler calls for MO messages. It reports false for
+// a message the provider already reported.
+func ReceiveInbound(ctx context.Context, provider string, in models.ProviderInbound) (*models.InboundMessage, bool, error) {
+	m := &models.InboundMessage{
+		ID:                uuid.New().String(),
+		Provider:          provider,
+		ProviderMessageID: in.MessageID,
+		From:              NormalizeNumber(in.From),
+		To:                NormalizeNumber(in.To),
+		Text:              in.Text,
+		Keyword:           InboundKeyword(in.Text),
+		WebhookStatus:     "none",
+		ReceivedAt:        time.Now().UTC(),
+	}
+	if in.ReceivedAt != nil {
+		m.ReceivedAt = in.ReceivedAt.UTC()
+	}
+
+	route, err := db.MatchInboundRoute(ctx, m.To, m.Keyword)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, false, err
+	}
+	if route != nil {
+		m.UserID = route.UserID
+		if route.WebhookURL != "" {
+			m.WebhookStatus = "pending"
+		}
+	}
+
+	stored, err := db.InsertInbound(ctx, m, route)
+	if err != nil {
+		return nil, false, err
+	}
+	if !stored {
+		metrics.InboundMessages.WithLabelValues("duplicate").Inc()
+		return m, false, nil
+	}
+
+	ctx = logger.WithUserID(ctx, m.UserID)
+	if route == nil {
+		metrics.InboundMessages.WithLabelValues("unrouted").Inc()
+		logger.WarnCtx(ctx, "Inbound message matched no route",
+			zap.String("inbound_id", m.ID), zap.String("to", m.To), zap.String("keyword", m.Keyword))
+		return m, true, nil
+	}
+	metrics.InboundMessages.WithLabelValues("routed").Inc()
+	logger.InfoCtx(ctx, "Inbound message routed",
+		zap.String("inbound_id", m.ID), zap.Int64("route_id", route.ID))
+	autoReply(ctx, m)
+	return m, true, nil
+}
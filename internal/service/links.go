@@ -0,0 +1,71 @@
+package service
+
+import (
+	"arvan-sms-gateway/internal/models"
+	"crypto/rand"
+	"errors"
+	"math/big"
+	"regexp"
+	"strings"
+)
+
+// ErrLinksDisabled is returned for messages asking for short links when
+// short_link_base_url is not configured.
+var ErrLinksDisabled = errors.New("link shortening is not configured")
+
+var linkPattern = regexp.MustCompile(`https?://[^\s<>"]+`)
+
+const (
+	tokenAlphabet = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+	tokenLength   = 8
+)
+
+func newLinkToken() (string, error) {
+	token := make([]byte, tokenLength)
+	for i := range token {
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(tokenAlphabet))))
+		if err != nil {
+			return "", err
+		}
+		token[i] = tokenAlphabet[n.Int64()]
+	}
+	return string(token), nil
+}
+
+// ShortLinkURL is the public URL of a short link token.
+func ShortLinkURL(base, token string) string {
+	return strings.TrimRight(base, "/") + "/l/" + token
+}
+
+// applyShortLinks replaces the URLs in a message asking for it with short
+// links under base, one token per distinct URL, and keeps them in req.Links
+// for InsertMessage.
+func applyShortLinks(req *models.SMSRequest, base string) error {
+	if !req.ShortenLinks {
+		return nil
+	}
+	if base == "" {
+		return ErrLinksDisabled
+	}
+	prefix := ShortLinkURL(base, "")
+	tokens := map[string]string{}
+	var err error
+	req.Message = linkPattern.ReplaceAllStringFunc(req.Message, func(u string) string {
+		// sentence punctuation after a URL is not part of it
+		trimmed := strings.TrimRight(u, ".,;:!?)]}'")
+		rest := u[len(trimmed):]
+		if err != nil || strings.HasPrefix(trimmed, prefix) {
+			return u
+		}
+		token, ok := tokens[trimmed]
+		if !ok {
+			if token, err = newLinkToken(); err != nil {
+				return u
+			}
+			tokens[trimmed] = token
+			req.Links = append(req.Links, models.ShortLink{Token: token, URL: trimmed})
+		}
+		return ShortLinkURL(base, token) + rest
+	})
+	return err
+}
@@ -0,0 +1,109 @@
+package service
+
+import (
+	"arvan-sms-gateway/internal/config"
+	"arvan-sms-gateway/internal/db"
+	"arvan-sms-gateway/internal/logger"
+	"arvan-sms-gateway/internal/metrics"
+	"arvan-sms-gateway/internal/models"
+	"arvan-sms-gateway/internal/otp"
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const otpEndpoint = "/otp/send"
+
+type OTPResult struct {
+	ServiceResult
+	ExpiresAt  time.Time
+	RetryAfter time.Duration
+}
+
+// SendOTP generates a code, stores its hash and sends it as an otp-class
+// message through the regular send path, so it is reserved, charged and
+// tracked like any other SMS.
+func SendOTP(ctx context.Context, req models.OTPSendRequest, cfg *config.Config) (*OTPResult, error) {
+	length, ttl, template := cfg.OTPLength, cfg.OTPTTL, cfg.OTPTemplate
+	if req.Length != 0 {
+		length = req.Length
+	}
+	if req.TTLSeconds != 0 {
+		ttl = time.Duration(req.TTLSeconds) * time.Second
+	}
+	if req.Template != "" {
+		template = req.Template
+	}
+
+	code, err := otp.Generate(length)
+	if err != nil {
+		return &OTPResult{ServiceResult: ServiceResult{StatusCode: http.StatusInternalServerError, Message: "code generation failed"}}, err
+	}
+	messageID := uuid.New().String()
+	ctx = logger.WithUserID(logger.WithMessageID(ctx, messageID), req.UserID)
+
+	wait, err := otp.Issue(ctx, req.UserID, req.PhoneNumber, code, messageID, ttl, cfg.OTPResendCooldown)
+	if errors.Is(err, otp.ErrCooldown) {
+		metrics.OTPEvents.WithLabelValues("cooldown").Inc()
+		return &OTPResult{
+			ServiceResult: ServiceResult{StatusCode: http.StatusTooManyRequests, Message: "a code was sent to this number recently"},
+			RetryAfter:    wait,
+		}, nil
+	}
+	if err != nil {
+		logger.ErrorCtx(ctx, "Failed to store OTP", zap.Error(err))
+		return &OTPResult{ServiceResult: ServiceResult{StatusCode: http.StatusServiceUnavailable, Message: "otp store unavailable"}}, err
+	}
+
+	// the message is useless once the code has expired
+	expires := time.Now().Add(ttl).UTC()
+	sms := models.SMSRequest{
+		UserID:      req.UserID,
+		PhoneNumber: req.PhoneNumber,
+		Message:     strings.ReplaceAll(template, "{code}", code),
+		OTPMasked:   strings.ReplaceAll(template, "{code}", strings.Repeat("*", length)),
+		MessageID:   messageID,
+		Priority:    models.PriorityOTP,
+		ExpiresAt:   &expires,
+	}
+	result, err := processSMS(ctx, sms, cfg, otpEndpoint)
+	if err != nil || result.StatusCode != http.StatusOK {
+		otp.Cancel(ctx, req.UserID, req.PhoneNumber)
+		return &OTPResult{ServiceResult: *result}, err
+	}
+
+	metrics.OTPEvents.WithLabelValues("issued").Inc()
+	return &OTPResult{ServiceResult: *result, ExpiresAt: expires}, nil
+}
+
+// VerifyOTP checks a code and records a successful verification on the
+// code's message. It returns the message id and, for a wrong code, the
+// attempts left; errors from the otp package describe why it failed.
+func VerifyOTP(ctx context.Context, req models.OTPVerifyRequest, cfg *config.Config) (string, int, error) {
+	messageID, left, err := otp.Verify(ctx, req.UserID, req.PhoneNumber, req.Code, cfg.OTPMaxAttempts)
+	switch {
+	case errors.Is(err, otp.ErrMismatch):
+		metrics.OTPEvents.WithLabelValues("mismatch").Inc()
+		return "", left, err
+	case errors.Is(err, otp.ErrNotFound):
+		metrics.OTPEvents.WithLabelValues("not_found").Inc()
+		return "", 0, err
+	case errors.Is(err, otp.ErrTooManyAttempts):
+		metrics.OTPEvents.WithLabelValues("locked").Inc()
+		return "", 0, err
+	case err != nil:
+		logger.ErrorCtx(ctx, "OTP verification failed", zap.Error(err))
+		return "", 0, err
+	}
+
+	metrics.OTPEvents.WithLabelValues("verified").Inc()
+	ctx = logger.WithUserID(logger.WithMessageID(ctx, messageID), req.UserID)
+	if err := db.MarkOTPVerified(ctx, messageID); err != nil {
+		logger.ErrorCtx(ctx, "Failed to record OTP verification", zap.Error(err))
+	}
+	return messageID, left, nil
+}
@@ -0,0 +1,146 @@
+package service
+
+import (
+	"arvan-sms-gateway/internal/db"
+	"arvan-sms-gateway/internal/logger"
+	"arvan-sms-gateway/internal/metrics"
+	"arvan-sms-gateway/internal/models"
+	"arvan-sms-gateway/internal/quota"
+	"context"
+	"fmt"
+	"go.uber.org/zap"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// CodeQuotaExceeded marks a send rejected for a quota rather than for the
+// per-second rate limit.
+const CodeQuotaExceeded = "quota_exceeded"
+
+// quotaCacheTTL bounds how long a replica keeps using a user's quota
+// definitions after another replica changed them.
+const quotaCacheTTL = 30 * time.Second
+
+type cachedQuotas struct {
+	quotas   []models.Quota
+	loadedAt time.Time
+}
+
+var (
+	quotaCacheMu sync.Mutex
+	quotaCache   = map[string]cachedQuotas{}
+)
+
+// userQuotas returns the user's quota definitions, from the cache when fresh.
+func userQuotas(ctx context.Context, userID string) ([]models.Quota, error) {
+	quotaCacheMu.Lock()
+	c, ok := quotaCache[userID]
+	quotaCacheMu.Unlock()
+	if ok && time.Since(c.loadedAt) < quotaCacheTTL {
+		return c.quotas, nil
+	}
+	quotas, err := db.ListQuotas(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	quotaCacheMu.Lock()
+	quotaCache[userID] = cachedQuotas{quotas: quotas, loadedAt: time.Now()}
+	quotaCacheMu.Unlock()
+	return quotas, nil
+}
+
+// ForgetQuotas drops the cached definitions of a user whose quotas changed.
+func ForgetQuotas(userID string) {
+	quotaCacheMu.Lock()
+	delete(quotaCache, userID)
+	quotaCacheMu.Unlock()
+}
+
+// quotaTake is what takeQuota counted, for releaseQuota.
+type quotaTake struct {
+	quotas []models.Quota
+	n      int64
+	at     time.Time
+}
+
+// takeQuota counts up to n messages of priority against the user's quotas
+// that cover it. It returns the take, possibly of fewer messages when
+// partial, and the quota that limited it. When the quotas cannot be read or
+// counted it returns an error and takes nothing, unless quota_fail_open lets
+// the messages through uncounted.
+func takeQuota(ctx context.Context, userID string, priority models.Priority, n int64, partial bool) (*quotaTake, *models.Quota, error) {
+	all, err := userQuotas(ctx, userID)
+	if err != nil {
+		return quotaUnavailable(ctx, n, "Failed to load quotas", err)
+	}
+	var quotas []models.Quota
+	for _, q := range all {
+		if q.Priority == "" || q.Priority == priority {
+			quotas = append(quotas, q)
+		}
+	}
+	t := &quotaTake{quotas: quotas, at: time.Now()}
+	taken, limiting, err := quota.Take(ctx, quotas, n, partial, t.at)
+	if err != nil {
+		return quotaUnavailable(ctx, n, "Quota counters unavailable", err)
+	}
+	t.n = taken
+	return t, limiting, nil
+}
+
+func quotaUnavailable(ctx context.Context, n int64, msg string, err error) (*quotaTake, *models.Quota, error) {
+	if serviceConfig != nil && serviceConfig.QuotaFailOpen {
+		logger.ErrorCtx(ctx, msg+", not enforcing quotas", zap.Error(err))
+		return &quotaTake{n: n}, nil, nil
+	}
+	logger.ErrorCtx(ctx, msg+", rejecting the send", zap.Error(err))
+	return &quotaTake{}, nil, err
+}
+
+// releaseQuota gives back n messages of a take that were not sent.
+func releaseQuota(ctx context.Context, t *quotaTake, n int64) {
+	if t == nil || n <= 0 {
+		return
+	}
+	if err := quota.Release(ctx, t.quotas, n, t.at); err != nil {
+		logger.WarnCtx(ctx, "Failed to release quota", zap.Error(err))
+	}
+}
+
+func quotaExceeded(q *models.Quota) *ServiceResult {
+	metrics.QuotaRejections.WithLabelValues(string(q.Period)).Inc()
+	class := "all"
+	if q.Priority != "" {
+		class = string(q.Priority)
+	}
+	period := "daily"
+	if q.Period == models.QuotaMonth {
+		period = "monthly"
+	}
+	return &ServiceResult{
+		StatusCode: http.StatusTooManyRequests,
+		Code:       CodeQuotaExceeded,
+		Message:    fmt.Sprintf("%s quota of %d messages (%s) exceeded", period, q.Limit, class),
+	}
+}
+
+// QuotaUsage returns the user's quotas with what was sent in the current
+// periods.
+func QuotaUsage(ctx context.Context, userID string) ([]models.QuotaUsage, error) {
+	quotas, err := db.ListQuotas(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	used, err := quota.Usage(ctx, quotas, now)
+	if err != nil {
+		return nil, err
+	}
+	usage := make([]models.QuotaUsage, len(quotas))
+	for i, q := range quotas {
+		_, resets := quota.Window(q.Period, now)
+		usage[i] = models.QuotaUsage{Quota: q, Used: used[i], Remaining: max(q.Limit-used[i], 0), ResetsAt: resets}
+	}
+	return usage, nil
+}
@@ -0,0 +1,60 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"arvan-sms-gateway/internal/config"
+	"arvan-sms-gateway/internal/models"
+	"arvan-sms-gateway/internal/quota"
+	"github.com/alicebob/miniredis/v2"
+)
+
+// withQuotas caches quotas for userID, so no database is needed.
+func withQuotas(t *testing.T, userID string, quotas ...models.Quota) {
+	t.Helper()
+	quotaCacheMu.Lock()
+	quotaCache[userID] = cachedQuotas{quotas: quotas, loadedAt: time.Now()}
+	quotaCacheMu.Unlock()
+	t.Cleanup(func() { ForgetQuotas(userID) })
+}
+
+func TestTakeQuotaEnforcesLimit(t *testing.T) {
+	srv := miniredis.RunT(t)
+	if err := quota.Init(srv.Addr(), "UTC"); err != nil {
+		t.Fatal(err)
+	}
+	serviceConfig = &config.Config{}
+	withQuotas(t, "u1", models.Quota{UserID: "u1", Period: models.QuotaDay, Limit: 2})
+
+	for i := 0; i < 2; i++ {
+		if take, limiting, err := takeQuota(context.Background(), "u1", models.PriorityTransactional, 1, false); err != nil || limiting != nil || take.n != 1 {
+			t.Fatalf("send %d: take = %+v, limiting = %v, err = %v", i, take, limiting, err)
+		}
+	}
+	if _, limiting, err := takeQuota(context.Background(), "u1", models.PriorityTransactional, 1, false); err != nil || limiting == nil {
+		t.Fatalf("send over the limit: limiting = %v, err = %v; want the daily quota", limiting, err)
+	}
+}
+
+func TestTakeQuotaWhileRedisIsDown(t *testing.T) {
+	srv := miniredis.RunT(t)
+	if err := quota.Init(srv.Addr(), "UTC"); err != nil {
+		t.Fatal(err)
+	}
+	srv.Close()
+	withQuotas(t, "u2", models.Quota{UserID: "u2", Period: models.QuotaDay, Limit: 10})
+
+	serviceConfig = &config.Config{}
+	take, _, err := takeQuota(context.Background(), "u2", models.PriorityTransactional, 1, false)
+	if err == nil || take.n != 0 {
+		t.Fatalf("fail closed: take = %d, err = %v; want nothing taken and an error", take.n, err)
+	}
+
+	serviceConfig = &config.Config{QuotaFailOpen: true}
+	take, _, err = takeQuota(context.Background(), "u2", models.PriorityTransactional, 1, false)
+	if err != nil || take.n != 1 {
+		t.Fatalf("fail open: take = %d, err = %v; want the message let through", take.n, err)
+	}
+}
@@ -1,74 +1,308 @@
 package service
 
 import (
+	"arvan-sms-gateway/internal/alerts"
+	"arvan-sms-gateway/internal/breaker"
 	"arvan-sms-gateway/internal/config"
 	"arvan-sms-gateway/internal/db"
 	"arvan-sms-gateway/internal/logger"
+	"arvan-sms-gateway/internal/metrics"
 	"arvan-sms-gateway/internal/models"
 	"arvan-sms-gateway/internal/queue"
+	"arvan-sms-gateway/internal/quiethours"
 	"arvan-sms-gateway/internal/reservation"
+	"context"
 	"encoding/json"
+	"errors"
+	"github.com/google/uuid"
 	"go.uber.org/zap"
 	"net/http"
+	"time"
 )
 
 type ServiceResult struct {
-	StatusCode int
-	Message    string
-	MessageID  string
+	StatusCode    int
+	Code          string // machine-readable reason, e.g. CodeQuotaExceeded
+	Message       string
+	MessageID     string
+	DeferredUntil time.Time // set when the message waits for quiet hours to end
 }
 
-var reserverService *reservation.Service
+var (
+	reserverService *reservation.Service
+	serviceConfig   *config.Config
+)
 
 func InitService(cfg *config.Config) {
-	reserverService = reservation.NewService(db.DB, cfg.RedisAddr)
+	serviceConfig = cfg
+	reserverService = reservation.NewService(db.DB, cfg.RedisAddr, cfg.ReservationTTL)
 	logger.Info("Reservation service initialized with Redis + Postgres fallback")
 }
 
-func ProcessSMSRequest(req models.SMSRequest, cfg *config.Config) (*ServiceResult, error) {
+// Reservations exposes the reservation service for background jobs.
+func Reservations() *reservation.Service {
+	return reserverService
+}
+
+// sendEndpoint and systemEndpoint label the stage latencies of messages
+// accepted by the API and of messages the gateway sends on its own.
+const (
+	sendEndpoint   = "/send-sms"
+	systemEndpoint = "system"
+)
+
+// messageCost is the number of tokens reserved and charged per message.
+const messageCost = 1
+
+func ProcessSMSRequest(ctx context.Context, req models.SMSRequest, cfg *config.Config) (*ServiceResult, error) {
+	return processSMS(ctx, req, cfg, sendEndpoint)
+}
+
+func processSMS(ctx context.Context, req models.SMSRequest, cfg *config.Config, endpoint string) (*ServiceResult, error) {
 	if req.MessageID == "" {
 		return &ServiceResult{StatusCode: http.StatusBadRequest, Message: "message_id is required"}, nil
 	}
+	priority, ok := models.ParsePriority(string(req.Priority))
+	if !ok {
+		return &ServiceResult{StatusCode: http.StatusBadRequest, Message: "invalid priority"}, nil
+	}
+	req.Priority = priority
+	applyValidity(&req, cfg)
+	if err := applyShortLinks(&req, cfg.ShortLinkBaseURL); err != nil {
+		if errors.Is(err, ErrLinksDisabled) {
+			return &ServiceResult{StatusCode: http.StatusBadRequest, Message: err.Error()}, nil
+		}
+		return &ServiceResult{StatusCode: http.StatusInternalServerError, Message: "short link error"}, err
+	}
+
+	ctx = logger.WithUserID(logger.WithMessageID(ctx, req.MessageID), req.UserID)
 
-	if err := db.InsertMessage(req, "queued"); err != nil {
-		logger.Error("Failed to insert message", zap.Error(err))
+	start := time.Now()
+	err := db.InsertMessage(ctx, req, models.StatusQueued)
+	observeStage(endpoint, "db_insert", start)
+	if err != nil {
+		logger.ErrorCtx(ctx, "Failed to insert message", zap.Error(err))
 		return &ServiceResult{StatusCode: http.StatusInternalServerError, Message: "duplicate message"}, err //TODO fixed it handel error
 	}
 
-	userData, err := GetUserData(req.UserID)
+	userData, err := GetUserData(ctx, req.UserID)
 	if err != nil {
-		logger.Error("Failed to fetch user", zap.Error(err))
+		logger.ErrorCtx(ctx, "Failed to fetch user", zap.Error(err))
+		setStatus(ctx, req.MessageID, models.StatusFailed, "user fetch error")
 		return &ServiceResult{StatusCode: http.StatusInternalServerError, Message: "user fetch error"}, err
 	}
 
-	topic := cfg.KafkaTopicNormal
+	if !userData.CanSend(req.Priority) {
+		setStatus(ctx, req.MessageID, models.StatusRejected, "priority "+string(req.Priority)+" not allowed")
+		return &ServiceResult{StatusCode: http.StatusForbidden, Message: "priority " + string(req.Priority) + " not allowed for this user"}, nil
+	}
+	if req.Priority == models.PriorityMarketing {
+		optedOut, err := db.OptedOut(ctx, req.UserID, []string{NormalizeNumber(req.PhoneNumber)})
+		if err != nil {
+			logger.ErrorCtx(ctx, "Failed to check opt-out", zap.Error(err))
+			setStatus(ctx, req.MessageID, models.StatusFailed, "opt-out check error")
+			return &ServiceResult{StatusCode: http.StatusInternalServerError, Message: "database error"}, err
+		}
+		if len(optedOut) > 0 {
+			setStatus(ctx, req.MessageID, models.StatusRejected, "recipient opted out")
+			return &ServiceResult{StatusCode: http.StatusForbidden, Message: "recipient opted out of marketing messages"}, nil
+		}
+	}
+
+	take, limiting, err := takeQuota(ctx, req.UserID, req.Priority, 1, false)
+	if err != nil {
+		setStatus(ctx, req.MessageID, models.StatusFailed, "quota check unavailable")
+		return &ServiceResult{StatusCode: http.StatusServiceUnavailable, Message: "quota check unavailable"}, err
+	}
+	if limiting != nil {
+		setStatus(ctx, req.MessageID, models.StatusRejected, "quota exceeded")
+		return quotaExceeded(limiting), nil
+	}
+
+	msg := models.QueuedSMS{SMSRequest: req}
+	topic := req.Priority.Topic(cfg.KafkaTopicNormal)
 	if userData.IsVIP {
-		topic = cfg.KafkaTopicVIP
+		topic = req.Priority.Topic(cfg.KafkaTopicVIP)
 	} else {
-		_, ok, err := reserverService.Reserve(req.UserID, 1)
+		start := time.Now()
+		resID, ok, err := reserverService.Reserve(ctx, req.UserID, req.MessageID, messageCost)
+		observeStage(endpoint, "reserve", start)
 		if err != nil {
-			logger.Error("Reservation error", zap.Error(err))
+			releaseQuota(ctx, take, 1)
+			logger.ErrorCtx(ctx, "Reservation error", zap.Error(err))
+			setStatus(ctx, req.MessageID, models.StatusFailed, "reservation error")
 			return &ServiceResult{StatusCode: http.StatusInternalServerError, Message: "reservation error"}, err
 		}
 		if !ok {
-			db.UpdateMessageStatus(req.MessageID, "rejected")
+			releaseQuota(ctx, take, 1)
+			setStatus(ctx, req.MessageID, models.StatusRejected, "insufficient balance")
 			return &ServiceResult{StatusCode: http.StatusBadRequest, Message: "insufficient balance"}, nil
 		}
+		msg.ReservationID = resID
 	}
 
-	data, _ := json.Marshal(req)
-	if err := queue.SendMessage(topic, req.UserID, string(data)); err != nil {
-		logger.Error("Kafka enqueue error", zap.Error(err))
-		if !userData.IsVIP {
-			_ = reserverService.Rollback(req.UserID, 1)
+	result, err := enqueue(ctx, topic, msg, endpoint)
+	if result.StatusCode != http.StatusOK {
+		releaseQuota(ctx, take, 1)
+	}
+	return result, err
+}
+
+// enqueue produces msg to topic. When Kafka does not accept it the message is
+// parked for a later republish, and only if that fails too is its reservation
+// refunded and the message failed.
+func enqueue(ctx context.Context, topic string, msg models.QueuedSMS, endpoint string) (*ServiceResult, error) {
+	data, _ := json.Marshal(msg)
+	if msg.Priority == models.PriorityMarketing {
+		if until := quiethours.Current().Until(msg.PhoneNumber, time.Now()); !until.IsZero() {
+			return deferMessage(ctx, topic, msg, string(data), until)
+		}
+	}
+
+	start := time.Now()
+	err := queue.SendMessage(ctx, topic, msg.UserID, string(data))
+	observeStage(endpoint, "produce", start)
+	if err != nil {
+		logger.ErrorCtx(ctx, "Kafka enqueue error", zap.Error(err))
+		// Keep the message (and its reservation) and let the parked-message
+		// job republish it once Kafka is back.
+		perr := park(ctx, db.ParkedMessage{
+			MessageID: msg.MessageID,
+			Topic:     topic,
+			Key:       msg.UserID,
+			Payload:   string(data),
+			Headers:   queue.Headers(ctx),
+		}, err)
+		if perr == nil {
+			return &ServiceResult{
+				StatusCode: http.StatusOK,
+				Message:    "pending",
+				MessageID:  msg.MessageID,
+			}, nil
+		}
+		logger.ErrorCtx(ctx, "Failed to park message", zap.Error(perr))
+		if msg.ReservationID != "" {
+			if _, err := reserverService.Refund(ctx, msg.ReservationID, "kafka enqueue failed"); err != nil {
+				logger.ErrorCtx(ctx, "Reservation refund failed",
+					zap.String("reservation_id", msg.ReservationID), zap.Error(err))
+			}
 		}
-		db.UpdateMessageStatus(req.MessageID, "error")
+		setStatus(ctx, msg.MessageID, models.StatusFailed, "kafka enqueue failed")
 		return &ServiceResult{StatusCode: http.StatusInternalServerError, Message: "kafka error"}, err
 	}
 
 	return &ServiceResult{
 		StatusCode: http.StatusOK,
 		Message:    "pending",
-		MessageID:  req.MessageID,
+		MessageID:  msg.MessageID,
 	}, nil
 }
+
+// applyValidity resolves the request's validity, or its priority's default,
+// into ExpiresAt, which the worker checks before sending.
+func applyValidity(req *models.SMSRequest, cfg *config.Config) {
+	if req.ExpiresAt != nil {
+		return
+	}
+	validity := time.Duration(req.Validity) * time.Second
+	if validity == 0 {
+		switch req.Priority {
+		case models.PriorityOTP:
+			validity = cfg.ValidityOTP
+		case models.PriorityMarketing:
+			validity = cfg.ValidityMarketing
+		default:
+			validity = cfg.ValidityTransactional
+		}
+	}
+	if validity > 0 {
+		expires := time.Now().Add(validity).UTC()
+		req.ExpiresAt = &expires
+	}
+}
+
+// deferMessage holds a marketing message accepted during quiet hours; the
+// parked-message job queues it again when they end.
+func deferMessage(ctx context.Context, topic string, msg models.QueuedSMS, payload string, until time.Time) (*ServiceResult, error) {
+	err := db.DeferMessage(ctx, db.ParkedMessage{
+		MessageID: msg.MessageID,
+		Topic:     topic,
+		Key:       msg.UserID,
+		Payload:   payload,
+		Headers:   queue.Headers(ctx),
+	}, "quiet hours until "+until.Format(time.RFC3339), time.Until(until))
+	if err != nil {
+		logger.ErrorCtx(ctx, "Failed to defer message", zap.Error(err))
+		if msg.ReservationID != "" {
+			if _, err := reserverService.Refund(ctx, msg.ReservationID, "defer failed"); err != nil {
+				logger.ErrorCtx(ctx, "Reservation refund failed",
+					zap.String("reservation_id", msg.ReservationID), zap.Error(err))
+			}
+		}
+		setStatus(ctx, msg.MessageID, models.StatusFailed, "defer failed")
+		return &ServiceResult{StatusCode: http.StatusInternalServerError, Message: "defer error"}, err
+	}
+	metrics.ParkedMessages.WithLabelValues(db.CauseQuietHours).Inc()
+	logger.InfoCtx(ctx, "Message deferred until quiet hours end", zap.Time("until", until))
+	return &ServiceResult{
+		StatusCode:    http.StatusOK,
+		Message:       "deferred",
+		MessageID:     msg.MessageID,
+		DeferredUntil: until,
+	}, nil
+}
+
+// AlertSMSSender returns the sender used for low-balance SMS, or nil when no
+// sender account is configured.
+func AlertSMSSender() alerts.SMSSender {
+	if serviceConfig == nil || serviceConfig.AlertSenderUserID == "" {
+		return nil
+	}
+	return SendSystemSMS
+}
+
+// SendSystemSMS sends a notification on behalf of the gateway itself, from
+// and billed to the ALERT_SENDER_USER_ID account.
+func SendSystemSMS(phone, text string) error {
+	if serviceConfig.AlertSenderUserID == "" {
+		return errors.New("ALERT_SENDER_USER_ID is not configured")
+	}
+	req := models.SMSRequest{
+		UserID:      serviceConfig.AlertSenderUserID,
+		PhoneNumber: phone,
+		Message:     text,
+		MessageID:   uuid.New().String(),
+	}
+	result, err := processSMS(context.Background(), req, serviceConfig, systemEndpoint)
+	if err != nil {
+		return err
+	}
+	if result.StatusCode != http.StatusOK {
+		return errors.New(result.Message)
+	}
+	return nil
+}
+
+// park stores a message Kafka did not accept for a republish once the Kafka
+// breaker allows a trial.
+func park(ctx context.Context, p db.ParkedMessage, cause error) error {
+	delay := max(time.Until(breaker.Kafka().NextTrial()), 0)
+	if err := db.ParkMessage(ctx, p, "kafka", cause.Error(), delay); err != nil {
+		return err
+	}
+	metrics.ParkedMessages.WithLabelValues("kafka").Inc()
+	logger.WarnCtx(ctx, "Message parked until Kafka is available", zap.Duration("retry_in", delay))
+	return nil
+}
+
+func observeStage(endpoint, stage string, start time.Time) {
+	metrics.RequestDuration.WithLabelValues(endpoint, stage).Observe(time.Since(start).Seconds())
+}
+
+func setStatus(ctx context.Context, messageID string, status models.MessageStatus, reason string) {
+	if err := db.TransitionMessage(ctx, messageID, status, reason); err != nil {
+		logger.ErrorCtx(ctx, "Failed to update message status",
+			zap.String("status", string(status)),
+			zap.Error(err))
+	}
+}
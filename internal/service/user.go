@@ -3,29 +3,44 @@ package service
 import (
 	"arvan-sms-gateway/internal/cache"
 	"arvan-sms-gateway/internal/db"
+	"arvan-sms-gateway/internal/models"
+	"context"
 	"time"
 )
 
 type UserData struct {
-	IsVIP   bool
-	Balance int64
+	IsVIP      bool
+	Balance    int64
+	Priorities []string
 }
 
-func GetUserData(userID string) (*UserData, error) {
-	cached, err := cache.GetUser(userID)
-	if err == nil && cached != nil {
-		return &UserData{IsVIP: cached.IsVIP, Balance: cached.Balance}, nil
+// CanSend reports whether the user may send messages of class p.
+func (u *UserData) CanSend(p models.Priority) bool {
+	for _, allowed := range u.Priorities {
+		if allowed == string(p) {
+			return true
+		}
+	}
+	return false
+}
+
+func GetUserData(ctx context.Context, userID string) (*UserData, error) {
+	cached, err := cache.GetUser(ctx, userID)
+	// entries cached before priority classes existed have no Priorities
+	if err == nil && cached != nil && cached.Priorities != nil {
+		return &UserData{IsVIP: cached.IsVIP, Balance: cached.Balance, Priorities: cached.Priorities}, nil
 	}
 
-	dbUser, err := db.GetUser(userID)
+	dbUser, err := db.GetUser(ctx, userID)
 	if err != nil {
 		return nil, err
 	}
 
-	data := &UserData{IsVIP: dbUser.IsVIP, Balance: dbUser.Balance}
-	_ = cache.SetUser(userID, &cache.UserData{
-		IsVIP:   dbUser.IsVIP,
-		Balance: dbUser.Balance,
+	data := &UserData{IsVIP: dbUser.IsVIP, Balance: dbUser.Balance, Priorities: dbUser.Priorities}
+	_ = cache.SetUser(ctx, userID, &cache.UserData{
+		IsVIP:      dbUser.IsVIP,
+		Balance:    dbUser.Balance,
+		Priorities: dbUser.Priorities,
 	}, 60*time.Second)
 
 	return data, nil
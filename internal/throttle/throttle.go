@@ -0,0 +1,183 @@
+// Package throttle keeps worker replicas together under the throughput each
+// provider allows. Send slots are handed out by a GCRA limiter in Redis, so
+// every replica draws from the same budget.
+package throttle
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sync"
+	"time"
+
+	"arvan-sms-gateway/internal/breaker"
+	"arvan-sms-gateway/internal/config"
+	"arvan-sms-gateway/internal/logger"
+	"arvan-sms-gateway/internal/metrics"
+	"arvan-sms-gateway/internal/ratelimit"
+	"arvan-sms-gateway/internal/tracing"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// ErrThrottled is returned by Wait when the next free slot is further away
+// than the caller is willing to block.
+var ErrThrottled = errors.New("provider rate limit reached")
+
+// reserve books the next send slot of the bucket in KEYS[1], which holds the
+// bucket's theoretical arrival time (TAT). ARGV[1] is the longest wait in
+// microseconds the caller accepts, ARGV[2] the emission interval in
+// microseconds and ARGV[3] the burst. It returns {1, wait} with the slot
+// booked, or {0, wait} without booking when wait is too long.
+var reserve = redis.NewScript(`
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000000 + tonumber(t[2])
+local interval, burst = tonumber(ARGV[2]), tonumber(ARGV[3])
+local tat = math.max(tonumber(redis.call('GET', KEYS[1]) or now), now)
+local wait = math.max(tat + interval - burst * interval - now, 0)
+if wait > tonumber(ARGV[1]) then
+    return {0, wait}
+end
+tat = tat + interval
+redis.call('SET', KEYS[1], string.format('%.0f', tat), 'PX', math.ceil((tat - now) / 1000) + 1000)
+return {1, wait}`)
+
+// release hands back a slot booked by reserve whose message was not sent.
+// ARGV[1] is the emission interval in microseconds.
+var release = redis.NewScript(`
+local tat = tonumber(redis.call('GET', KEYS[1]))
+if not tat then
+    return 0
+end
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000000 + tonumber(t[2])
+tat = tat - tonumber(ARGV[1])
+if tat <= now then
+    return redis.call('DEL', KEYS[1])
+end
+redis.call('SET', KEYS[1], string.format('%.0f', tat), 'PX', math.ceil((tat - now) / 1000) + 1000)
+return 1`)
+
+var (
+	rdb *redis.Client
+
+	mu        sync.Mutex
+	rawLimits string
+	limits    map[string]ratelimit.Limit
+)
+
+func Init(addr string) {
+	rdb = redis.NewClient(&redis.Options{Addr: addr, ContextTimeoutEnabled: true})
+	tracing.InstrumentRedis(rdb)
+	logger.Info("Provider throttle initialized")
+}
+
+// current returns the limits of the live config, parsed once per change.
+func current() map[string]ratelimit.Limit {
+	spec := config.Live().ProviderRateLimits
+	mu.Lock()
+	defer mu.Unlock()
+	if spec != rawLimits || limits == nil {
+		// validated on load and reload, so an error cannot happen here
+		limits, _ = ratelimit.ParseLimits(spec)
+		rawLimits = spec
+	}
+	return limits
+}
+
+// Wait blocks until provider, and sender if it has its own limit, may take
+// one more message, and returns how long it waited. When that would take
+// longer than maxWait it returns the wait still needed and ErrThrottled,
+// and books nothing. Without a limit for provider or sender it returns
+// immediately.
+func Wait(ctx context.Context, provider, sender string, maxWait time.Duration) (time.Duration, error) {
+	return wait(ctx, current(), provider, sender, maxWait)
+}
+
+type bucket struct {
+	key      string
+	interval int64 // microseconds between slots
+	burst    int
+}
+
+// wait books the sender's bucket first and the provider's only once the
+// sender's slot is due. The provider bucket then records the real send time,
+// so a slow sender does not hold back the other senders at its provider.
+func wait(ctx context.Context, all map[string]ratelimit.Limit, provider, sender string, maxWait time.Duration) (time.Duration, error) {
+	var buckets []bucket
+	add := func(name string) {
+		if l, ok := all[name]; ok {
+			buckets = append(buckets, bucket{"throttle:" + name, int64(math.Ceil(1e6 / l.Rate)), l.Burst})
+		}
+	}
+	if sender != "" {
+		add(provider + "/" + sender)
+	}
+	add(provider)
+	if len(buckets) == 0 || rdb == nil {
+		return 0, nil
+	}
+
+	var waited time.Duration
+	var booked []bucket
+	for _, b := range buckets {
+		d, err := b.take(ctx, maxWait-waited)
+		if err == nil {
+			booked = append(booked, b)
+			err = sleep(ctx, d)
+		}
+		if err != nil {
+			// the message is not sent now, so its slots are free again
+			for _, b := range booked {
+				b.release(ctx)
+			}
+			if errors.Is(err, ErrThrottled) {
+				return d, err
+			}
+			return waited + d, err
+		}
+		waited += d
+	}
+	metrics.ProviderThrottleWait.WithLabelValues(provider).Observe(waited.Seconds())
+	return waited, nil
+}
+
+func (b bucket) take(ctx context.Context, maxWait time.Duration) (time.Duration, error) {
+	var res []int64
+	err := breaker.Redis().Do(ctx, func(ctx context.Context) error {
+		var err error
+		res, err = reserve.Run(ctx, rdb, []string{b.key}, maxWait.Microseconds(), b.interval, b.burst).Int64Slice()
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	d := time.Duration(res[1]) * time.Microsecond
+	if res[0] == 0 {
+		return d, ErrThrottled
+	}
+	return d, nil
+}
+
+func (b bucket) release(ctx context.Context) {
+	err := breaker.Redis().Do(context.WithoutCancel(ctx), func(ctx context.Context) error {
+		return release.Run(ctx, rdb, []string{b.key}, b.interval).Err()
+	})
+	if err != nil {
+		logger.WarnCtx(ctx, "Failed to release provider send slot", zap.String("bucket", b.key), zap.Error(err))
+	}
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,105 @@
+package throttle
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"arvan-sms-gateway/internal/ratelimit"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func startRedis(t *testing.T) {
+	t.Helper()
+	srv := miniredis.RunT(t)
+	rdb = redis.NewClient(&redis.Options{Addr: srv.Addr()})
+	t.Cleanup(func() {
+		rdb.Close()
+		rdb = nil
+	})
+}
+
+func TestWaitWithoutLimit(t *testing.T) {
+	startRedis(t)
+	for i := 0; i < 10; i++ {
+		if d, err := wait(context.Background(), nil, "mock", "ACME", 0); d != 0 || err != nil {
+			t.Fatalf("send %d: wait = %s, %v; want no throttling", i, d, err)
+		}
+	}
+}
+
+func TestWaitBurstThenThrottled(t *testing.T) {
+	startRedis(t)
+	limits := map[string]ratelimit.Limit{"mock": {Rate: 1, Burst: 3}}
+	for i := 0; i < 3; i++ {
+		if d, err := wait(context.Background(), limits, "mock", "", 0); d != 0 || err != nil {
+			t.Fatalf("send %d within burst: wait = %s, %v", i, d, err)
+		}
+	}
+	d, err := wait(context.Background(), limits, "mock", "", 100*time.Millisecond)
+	if !errors.Is(err, ErrThrottled) {
+		t.Fatalf("send past burst: err = %v, want ErrThrottled", err)
+	}
+	if d < 900*time.Millisecond || d > time.Second {
+		t.Fatalf("send past burst: wait = %s, want about 1s", d)
+	}
+}
+
+// A sender waiting on its own low limit must not book the provider ahead of
+// time and slow down the other senders at that provider.
+func TestSlowSenderDoesNotHoldBackProvider(t *testing.T) {
+	startRedis(t)
+	limits := map[string]ratelimit.Limit{
+		"mock":      {Rate: 100, Burst: 1},
+		"mock/SLOW": {Rate: 2, Burst: 1},
+	}
+	if _, err := wait(context.Background(), limits, "mock", "SLOW", 0); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		// waits about 500ms for the sender's next slot
+		_, err := wait(ctx, limits, "mock", "SLOW", time.Second)
+		done <- err
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	for i := 0; i < 3; i++ {
+		d, err := wait(context.Background(), limits, "mock", "FAST", 100*time.Millisecond)
+		if err != nil {
+			t.Fatalf("other sender, send %d: wait = %s, %v", i, d, err)
+		}
+	}
+
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Fatalf("slow sender: err = %v, want context.Canceled", err)
+	}
+	// its canceled slot was handed back, so the next one is the one it waited for
+	if d, _ := wait(context.Background(), limits, "mock", "SLOW", 0); d > 500*time.Millisecond {
+		t.Fatalf("slow sender after cancel: wait = %s, want at most 500ms", d)
+	}
+}
+
+func TestThrottledProviderReleasesSenderSlot(t *testing.T) {
+	startRedis(t)
+	limits := map[string]ratelimit.Limit{
+		"mock":      {Rate: 1, Burst: 1},
+		"mock/ACME": {Rate: 1, Burst: 1},
+	}
+	if _, err := wait(context.Background(), limits, "mock", "OTHER", 0); err != nil {
+		t.Fatal(err)
+	}
+	// the sender has a free slot but the provider does not
+	if _, err := wait(context.Background(), limits, "mock", "ACME", 0); !errors.Is(err, ErrThrottled) {
+		t.Fatalf("err = %v, want ErrThrottled", err)
+	}
+	senderOnly := map[string]ratelimit.Limit{"mock/ACME": limits["mock/ACME"]}
+	if _, err := wait(context.Background(), senderOnly, "mock", "ACME", 0); err != nil {
+		t.Fatalf("sender slot was not released: %v", err)
+	}
+}
@@ -0,0 +1,86 @@
+// Package webhook posts to URLs customers register without letting them reach
+// the gateway's own network. Loopback, private, link-local and other
+// non-public addresses are refused when a URL is saved and again when it is
+// dialed, so a name that resolves inward later, or a redirect, is caught too.
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"syscall"
+	"time"
+)
+
+// ErrNotPublic is returned for a webhook host that resolves to an address
+// that is not publicly routable.
+var ErrNotPublic = errors.New("webhook host is not a public address")
+
+// cgnat is the shared address space of carrier-grade NAT (RFC 6598), which
+// netip does not count as private.
+var cgnat = netip.MustParsePrefix("100.64.0.0/10")
+
+var allowPrivate bool
+
+// Init sets whether webhooks may target non-public addresses, e.g. a
+// receiver on the local Docker network during development.
+func Init(allowPrivateAddrs bool) {
+	allowPrivate = allowPrivateAddrs
+}
+
+func public(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	return ip.IsGlobalUnicast() && !ip.IsPrivate() && !cgnat.Contains(ip)
+}
+
+// CheckURL validates a webhook URL before it is saved: an absolute http(s)
+// URL whose host resolves only to public addresses.
+func CheckURL(ctx context.Context, raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return errors.New("must be an absolute http(s) URL")
+	}
+	if allowPrivate {
+		return nil
+	}
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", u.Hostname())
+	if err != nil {
+		return fmt.Errorf("cannot resolve %s", u.Hostname())
+	}
+	for _, ip := range addrs {
+		if !public(ip) {
+			return ErrNotPublic
+		}
+	}
+	return nil
+}
+
+// control runs on every connection after its address was resolved.
+func control(_, address string, _ syscall.RawConn) error {
+	if allowPrivate {
+		return nil
+	}
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip, err := netip.ParseAddr(host)
+	if err != nil || !public(ip) {
+		return fmt.Errorf("%w: %s", ErrNotPublic, host)
+	}
+	return nil
+}
+
+// NewClient returns a client that only connects to public addresses. It
+// ignores proxy settings, which would bypass the check.
+func NewClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{Timeout: timeout, Control: control}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{Timeout: timeout, Transport: transport}
+}
@@ -0,0 +1,102 @@
+package worker
+
+import (
+	"context"
+	"sync"
+
+	"arvan-sms-gateway/internal/models"
+)
+
+// scheduler shares a worker's processing slots between the priority topics.
+// Records of every class wait for a slot; while several classes are waiting
+// each free slot goes to a class in proportion to its weight (smooth weighted
+// round robin), so OTPs are served first without starving marketing. A class
+// nobody else competes with gets every slot.
+type scheduler struct {
+	mu      sync.Mutex
+	slots   int
+	busy    int
+	weights map[models.Priority]int
+	credit  map[models.Priority]int
+	waiting map[models.Priority][]chan struct{}
+}
+
+func newScheduler(slots int, weights map[models.Priority]int) *scheduler {
+	return &scheduler{
+		slots:   slots,
+		weights: weights,
+		credit:  map[models.Priority]int{},
+		waiting: map[models.Priority][]chan struct{}{},
+	}
+}
+
+// acquire blocks until p is given a slot or ctx is done.
+func (s *scheduler) acquire(ctx context.Context, p models.Priority) error {
+	s.mu.Lock()
+	ch := make(chan struct{})
+	s.waiting[p] = append(s.waiting[p], ch)
+	s.grant()
+	s.mu.Unlock()
+
+	select {
+	case <-ch:
+		return nil
+	case <-ctx.Done():
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, w := range s.waiting[p] {
+		if w == ch {
+			s.waiting[p] = append(s.waiting[p][:i], s.waiting[p][i+1:]...)
+			return ctx.Err()
+		}
+	}
+	// granted while giving up: hand the slot on
+	s.busy--
+	s.grant()
+	return ctx.Err()
+}
+
+func (s *scheduler) release() {
+	s.mu.Lock()
+	s.busy--
+	s.grant()
+	s.mu.Unlock()
+}
+
+func (s *scheduler) grant() {
+	for s.busy < s.slots {
+		p, ok := s.next()
+		if !ok {
+			return
+		}
+		ch := s.waiting[p][0]
+		s.waiting[p] = s.waiting[p][1:]
+		s.busy++
+		close(ch)
+	}
+}
+
+// next picks the waiting class with the most credit after every waiting
+// class earned its weight.
+func (s *scheduler) next() (models.Priority, bool) {
+	var best models.Priority
+	total := 0
+	for _, p := range models.Priorities {
+		if len(s.waiting[p]) == 0 {
+			s.credit[p] = 0
+			continue
+		}
+		s.credit[p] += s.weights[p]
+		total += s.weights[p]
+		if best == "" || s.credit[p] > s.credit[best] {
+			best = p
+		}
+	}
+	if best == "" {
+		return "", false
+	}
+	s.credit[best] -= total
+	return best, true
+}
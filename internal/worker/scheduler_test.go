@@ -0,0 +1,140 @@
+package worker
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"arvan-sms-gateway/internal/models"
+)
+
+var testWeights = map[models.Priority]int{
+	models.PriorityOTP:           6,
+	models.PriorityTransactional: 3,
+	models.PriorityMarketing:     1,
+}
+
+// addWaiters adds n waiters of p without going through acquire, so grant can be
+// driven one slot at a time.
+func addWaiters(s *scheduler, p models.Priority, n int) {
+	for i := 0; i < n; i++ {
+		s.waiting[p] = append(s.waiting[p], make(chan struct{}))
+	}
+}
+
+// grantOne frees the single slot and reports which class got it.
+func grantOne(t *testing.T, s *scheduler) models.Priority {
+	t.Helper()
+	before := map[models.Priority]int{}
+	for _, p := range models.Priorities {
+		before[p] = len(s.waiting[p])
+	}
+	s.busy = 0
+	s.grant()
+	for _, p := range models.Priorities {
+		if len(s.waiting[p]) < before[p] {
+			return p
+		}
+	}
+	t.Fatal("no class was granted the slot")
+	return ""
+}
+
+func TestSchedulerSharesSlotsByWeight(t *testing.T) {
+	s := newScheduler(1, testWeights)
+	for _, p := range models.Priorities {
+		addWaiters(s, p, 100)
+	}
+
+	got := map[models.Priority]int{}
+	for i := 0; i < 50; i++ {
+		got[grantOne(t, s)]++
+	}
+	want := map[models.Priority]int{
+		models.PriorityOTP:           30,
+		models.PriorityTransactional: 15,
+		models.PriorityMarketing:     5,
+	}
+	for p, n := range want {
+		if got[p] != n {
+			t.Errorf("%s got %d of 50 slots, want %d (all: %v)", p, got[p], n, got)
+		}
+	}
+}
+
+func TestSchedulerDoesNotStarveLowWeight(t *testing.T) {
+	s := newScheduler(1, testWeights)
+	addWaiters(s, models.PriorityOTP, 100)
+	addWaiters(s, models.PriorityMarketing, 100)
+
+	// with weights 6 and 1 marketing is due at least once every 7 turns
+	since := 0
+	for i := 0; i < 70; i++ {
+		if grantOne(t, s) == models.PriorityMarketing {
+			since = 0
+			continue
+		}
+		if since++; since >= 7 {
+			t.Fatalf("marketing waited %d turns at turn %d", since, i)
+		}
+	}
+}
+
+func TestSchedulerLoneClassGetsEverySlot(t *testing.T) {
+	s := newScheduler(1, testWeights)
+	addWaiters(s, models.PriorityMarketing, 10)
+	for i := 0; i < 10; i++ {
+		if p := grantOne(t, s); p != models.PriorityMarketing {
+			t.Fatalf("turn %d went to %s", i, p)
+		}
+	}
+}
+
+func TestSchedulerLimitsConcurrency(t *testing.T) {
+	s := newScheduler(2, testWeights)
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		if err := s.acquire(ctx, models.PriorityTransactional); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	acquired := make(chan error, 1)
+	go func() { acquired <- s.acquire(ctx, models.PriorityOTP) }()
+	select {
+	case <-acquired:
+		t.Fatal("a third slot was handed out while both were busy")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	s.release()
+	select {
+	case err := <-acquired:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("released slot was not handed to the waiter")
+	}
+}
+
+func TestSchedulerAcquireCanceled(t *testing.T) {
+	s := newScheduler(1, testWeights)
+	if err := s.acquire(context.Background(), models.PriorityOTP); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := s.acquire(ctx, models.PriorityMarketing); err != context.Canceled {
+		t.Fatalf("acquire = %v, want context.Canceled", err)
+	}
+	if n := len(s.waiting[models.PriorityMarketing]); n != 0 {
+		t.Fatalf("canceled waiter still queued (%d waiting)", n)
+	}
+
+	s.release()
+	if s.busy != 0 {
+		t.Fatalf("busy = %d after release, want 0", s.busy)
+	}
+}
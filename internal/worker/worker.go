@@ -1,34 +1,64 @@
 package worker
 
 import (
+	"arvan-sms-gateway/internal/breaker"
+	"arvan-sms-gateway/internal/config"
 	"arvan-sms-gateway/internal/db"
+	"arvan-sms-gateway/internal/health"
 	"arvan-sms-gateway/internal/logger"
 	"arvan-sms-gateway/internal/metrics"
 	"arvan-sms-gateway/internal/models"
+	"arvan-sms-gateway/internal/queue"
+	"arvan-sms-gateway/internal/quiethours"
+	"arvan-sms-gateway/internal/reservation"
+	"arvan-sms-gateway/internal/throttle"
+	"arvan-sms-gateway/internal/tracing"
 	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"github.com/IBM/sarama"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
+	"sync/atomic"
 	"syscall"
 	"time"
 )
 
 const smsCost = 1
 
+// providerName labels send metrics until real provider integrations exist.
+const providerName = "mock"
+
+// providerIDAttempts bounds the tries to store the id of a message the
+// provider accepted before the session is restarted.
+const providerIDAttempts = 3
+
 type consumer struct {
-	isVIP bool
+	baseTopic    string
+	priorities   map[string]models.Priority // topic -> class
+	sched        *scheduler
+	group        string
+	isVIP        bool
+	reservations *reservation.Service
+	throttleWait time.Duration // longest wait for a provider send slot before parking
+	claimLease   time.Duration // age after which a claim that never reached the provider is taken over
+	session      atomic.Bool   // a consumer group session is active
 }
 
-func StartWorker(brokers []string, topic, group string, isVIP bool) {
-	config := sarama.NewConfig()
-	config.Version = sarama.V2_5_0_0
-	config.Consumer.Group.Rebalance.Strategy = sarama.BalanceStrategyRoundRobin
-	config.Consumer.Offsets.Initial = sarama.OffsetOldest
-	config.Consumer.Offsets.AutoCommit.Enable = false
-
-	cg, err := sarama.NewConsumerGroup(brokers, group, config)
+// StartWorker consumes the priority topics of baseTopic (see
+// models.Priority.Topic) in one consumer group.
+func StartWorker(cfg *config.Config, baseTopic, group string, isVIP bool) {
+	brokers := strings.Split(cfg.KafkaBrokers, ",")
+	cg, err := queue.NewConsumerGroup(brokers, group, cfg.KafkaInstanceID, cfg.KafkaInitialOffset)
 	if err != nil {
 		logger.Error("Failed to create consumer group", zap.Error(err))
 		return
@@ -45,15 +75,43 @@ func StartWorker(brokers []string, topic, group string, isVIP bool) {
 		cancel()
 	}()
 
-	handler := &consumer{isVIP: isVIP}
+	handler := &consumer{
+		baseTopic:    baseTopic,
+		priorities:   map[string]models.Priority{},
+		group:        group,
+		isVIP:        isVIP,
+		throttleWait: cfg.ProviderThrottleMaxWait,
+		claimLease:   cfg.ClaimLease,
+		sched: newScheduler(cfg.WorkerConcurrency, map[models.Priority]int{
+			models.PriorityOTP:           cfg.PriorityWeightOTP,
+			models.PriorityTransactional: cfg.PriorityWeightTransactional,
+			models.PriorityMarketing:     cfg.PriorityWeightMarketing,
+		}),
+	}
+	topics := make([]string, 0, len(models.Priorities))
+	for _, p := range models.Priorities {
+		topics = append(topics, p.Topic(baseTopic))
+		handler.priorities[p.Topic(baseTopic)] = p
+	}
+	if !isVIP {
+		handler.reservations = reservation.NewService(db.DB, cfg.RedisAddr, cfg.ReservationTTL)
+		health.Register("reservations", handler.reservations.Ping)
+	}
+	health.Register("kafka_consumer", func(context.Context) error {
+		if !handler.session.Load() {
+			return errors.New("no active consumer group session")
+		}
+		return nil
+	})
 
 	logger.Info("Worker started",
-		zap.String("topic", topic),
+		zap.Strings("topics", topics),
 		zap.String("group", group),
+		zap.String("instance_id", cfg.KafkaInstanceID),
 		zap.Bool("isVIP", isVIP))
 
 	for {
-		if err := cg.Consume(ctx, []string{topic}, handler); err != nil {
+		if err := cg.Consume(ctx, topics, handler); err != nil {
 			logger.Error("Kafka consume error", zap.Error(err))
 			time.Sleep(2 * time.Second)
 		}
@@ -65,89 +123,420 @@ func StartWorker(brokers []string, topic, group string, isVIP bool) {
 }
 
 func (c *consumer) Setup(sarama.ConsumerGroupSession) error {
+	c.session.Store(true)
 	logger.Info("Kafka consumer setup complete")
 	return nil
 }
 func (c *consumer) Cleanup(sarama.ConsumerGroupSession) error {
+	c.session.Store(false)
 	logger.Info("Kafka consumer cleanup complete")
 	return nil
 }
 
 func (c *consumer) ConsumeClaim(sess sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
 	for msg := range claim.Messages() {
-		logger.Info("Received Kafka message",
+		ctx := queue.ExtractContext(sess.Context(), msg)
+		logger.InfoCtx(ctx, "Received Kafka message",
 			zap.String("topic", claim.Topic()),
 			zap.Int32("partition", msg.Partition),
 			zap.Int64("offset", msg.Offset))
+		metrics.ConsumerLag.WithLabelValues(c.group, claim.Topic(), strconv.Itoa(int(msg.Partition))).
+			Set(float64(max(claim.HighWaterMarkOffset()-msg.Offset-1, 0)))
 
-		var req models.SMSRequest
+		var req models.QueuedSMS
 		if err := json.Unmarshal(msg.Value, &req); err != nil {
-			logger.Error("Invalid Kafka message payload", zap.Error(err))
+			logger.ErrorCtx(ctx, "Invalid Kafka message payload", zap.Error(err))
 			sess.MarkMessage(msg, "")
 			metrics.KafkaErrors.Inc()
 			continue
 		}
 
-		if c.isVIP {
-			handleVIP(req)
-		} else {
-			handleNormal(req)
+		ctx = logger.WithUserID(logger.WithMessageID(ctx, req.MessageID), req.UserID)
+		if err := c.sched.acquire(sess.Context(), c.priorities[claim.Topic()]); err != nil {
+			return nil
+		}
+		err := c.process(ctx, msg, req)
+		c.sched.release()
+		if err != nil {
+			// Leave the offset uncommitted; the record is redelivered after the
+			// session restarts and the claim below keeps it from being sent twice.
+			logger.ErrorCtx(ctx, "Message processing failed, restarting session", zap.Error(err))
+			return err
 		}
-
-		metrics.KafkaMessages.Inc()
-		metrics.QueueLength.Dec()
 
 		sess.MarkMessage(msg, "")
 		sess.Commit()
 	}
 	return nil
 }
-func handleVIP(req models.SMSRequest) {
-	logger.Info("Processing VIP SMS",
-		zap.String("message_id", req.MessageID),
-		zap.String("user_id", req.UserID),
+
+// process handles one record in a span that continues the trace of the
+// request that produced it; ctx already carries the record's headers.
+func (c *consumer) process(ctx context.Context, msg *sarama.ConsumerMessage, req models.QueuedSMS) error {
+	ctx, span := tracing.Tracer("worker").Start(ctx, "kafka.process "+msg.Topic,
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			attribute.String("messaging.system", "kafka"),
+			attribute.String("messaging.destination.name", msg.Topic),
+			attribute.Int("messaging.kafka.destination.partition", int(msg.Partition)),
+			attribute.Int64("messaging.kafka.message.offset", msg.Offset),
+			attribute.String("sms.message_id", req.MessageID)))
+	defer span.End()
+
+	if req.ExpiresAt != nil && !time.Now().Before(*req.ExpiresAt) {
+		expired, err := c.expire(ctx, req)
+		if err != nil || expired {
+			return err
+		}
+	}
+	if req.Priority == models.PriorityMarketing {
+		if until := quiethours.Current().Until(req.PhoneNumber, time.Now()); !until.IsZero() {
+			deferred, err := c.deferUntil(ctx, req, until)
+			if err != nil || deferred {
+				return err
+			}
+		}
+	}
+
+	// While the provider's breaker is open the message is parked instead of
+	// claimed, so it stays queued and is republished once a trial is due.
+	if wait := time.Until(breaker.Provider(providerName).NextTrial()); wait > 0 {
+		return c.park(ctx, req, wait, "provider circuit open")
+	}
+	// Replicas share the provider's send rate, and the sender's where it has
+	// its own limit. A short wait is absorbed here; a longer one parks the
+	// message rather than holding the partition.
+	wait, err := throttle.Wait(ctx, providerName, req.SenderID, c.throttleWait)
+	switch {
+	case errors.Is(err, throttle.ErrThrottled):
+		return c.park(ctx, req, wait, "provider rate limit")
+	case ctx.Err() != nil:
+		return ctx.Err()
+	case err != nil:
+		logger.WarnCtx(ctx, "Provider throttle unavailable, sending unthrottled", zap.Error(err))
+	}
+
+	if c.isVIP {
+		err = c.handleVIP(ctx, req)
+	} else {
+		err = c.handleNormal(ctx, req)
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	return err
+}
+
+// claim reports whether the worker owns the message: it returns StatusSending
+// when the message should be dispatched, or the status it already has (empty
+// when there is nothing to do).
+// A non-empty provider id means an earlier attempt already handed the message
+// to the provider and crashed before completing it, so it must not be resent.
+func (c *consumer) claim(ctx context.Context, req models.QueuedSMS) (string, models.MessageStatus, error) {
+	claimed, err := db.ClaimMessage(ctx, req.MessageID, c.claimLease)
+	if err != nil {
+		return "", "", err
+	}
+	if claimed {
+		return "", models.StatusSending, nil
+	}
+
+	status, providerID, err := db.GetMessageDispatch(ctx, req.MessageID)
+	if err == sql.ErrNoRows {
+		logger.WarnCtx(ctx, "Unknown message, skipping")
+		return "", "", nil
+	}
+	if err != nil {
+		return "", "", err
+	}
+	if status == models.StatusSending && providerID != "" {
+		logger.InfoCtx(ctx, "Resuming message already accepted by provider",
+			zap.String("provider_message_id", providerID))
+		return providerID, status, nil
+	}
+
+	logger.WarnCtx(ctx, "Duplicate delivery, message already claimed",
+		zap.String("status", string(status)))
+	if status == models.StatusSending {
+		// Claimed by another attempt that has not reached the provider yet. It
+		// is checked again once that claim's lease ran out, so a claim left by
+		// a crashed attempt is taken over instead of stranding the message.
+		return "", "", c.park(ctx, req, c.claimLease, "claimed by another attempt")
+	}
+	return "", status, nil
+}
+
+// dispatch sends a claimed message unless a previous attempt already did. It
+// returns breaker.ErrOpen without calling the provider when its breaker is
+// open.
+func (c *consumer) dispatch(ctx context.Context, req models.SMSRequest, providerID string) (bool, error) {
+	if providerID != "" {
+		return true, nil
+	}
+	provider := breaker.Provider(providerName)
+	if err := provider.Allow(); err != nil {
+		return false, err
+	}
+	start := time.Now()
+	_, span := tracing.Tracer("worker").Start(ctx, "provider.send",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.String("sms.provider", providerName)))
+	providerID, sent := sendSMS(req.PhoneNumber, req.SenderID, req.Message, validityPeriod(req.ExpiresAt, time.Now()))
+	if !sent {
+		span.SetStatus(codes.Error, "provider rejected message")
+		provider.Record(errors.New("provider rejected message"))
+	} else {
+		provider.Record(nil)
+	}
+	span.End()
+	topic := req.Priority.Topic(c.baseTopic)
+	metrics.WorkerSendDuration.WithLabelValues(topic, providerName).Observe(time.Since(start).Seconds())
+	outcome := "sent"
+	if !sent {
+		outcome = "failed"
+	}
+	metrics.WorkerSendOutcomes.WithLabelValues(topic, providerName, outcome).Inc()
+	if !sent {
+		return false, nil
+	}
+	// Without the provider id a redelivery cannot tell the message was sent,
+	// so the record is not acknowledged until the id is stored.
+	var err error
+	for attempt := 0; attempt < providerIDAttempts; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return true, fmt.Errorf("record provider message id %s: %w", providerID, ctx.Err())
+			case <-time.After(time.Duration(attempt) * 200 * time.Millisecond):
+			}
+		}
+		if err = db.SetProviderMessageID(ctx, req.MessageID, providerID); err == nil {
+			return true, nil
+		}
+		logger.ErrorCtx(ctx, "Failed to record provider message id",
+			zap.String("provider_message_id", providerID),
+			zap.Int("attempt", attempt+1),
+			zap.Error(err))
+		if err == sql.ErrNoRows {
+			// no longer sending, so another attempt owns the message
+			break
+		}
+	}
+	return true, fmt.Errorf("record provider message id %s: %w", providerID, err)
+}
+
+// requeue returns a claimed message that never reached the provider to
+// queued and parks it until the provider's breaker allows a trial.
+func (c *consumer) requeue(ctx context.Context, req models.QueuedSMS, cause error) error {
+	if err := db.TransitionMessage(ctx, req.MessageID, models.StatusQueued, "provider unavailable"); err != nil {
+		return err
+	}
+	return c.park(ctx, req, time.Until(breaker.Provider(providerName).NextTrial()), cause.Error())
+}
+
+// parked builds the record the gateway's parked-message job republishes to
+// the message's priority topic.
+func (c *consumer) parked(ctx context.Context, req models.QueuedSMS) (db.ParkedMessage, error) {
+	payload, err := json.Marshal(req)
+	if err != nil {
+		return db.ParkedMessage{}, err
+	}
+	return db.ParkedMessage{
+		MessageID: req.MessageID,
+		Topic:     req.Priority.Topic(c.baseTopic),
+		Key:       req.UserID,
+		Payload:   string(payload),
+		Headers:   queue.Headers(ctx),
+	}, nil
+}
+
+// park stores the message for a republish after delay.
+func (c *consumer) park(ctx context.Context, req models.QueuedSMS, delay time.Duration, reason string) error {
+	parked, err := c.parked(ctx, req)
+	if err != nil {
+		return err
+	}
+	if err := db.ParkMessage(ctx, parked, "provider", reason, max(delay, 0)); err != nil {
+		return err
+	}
+	metrics.ParkedMessages.WithLabelValues("provider").Inc()
+	logger.WarnCtx(ctx, "Message parked for retry",
+		zap.String("reason", reason),
+		zap.Duration("retry_in", max(delay, 0)))
+	return nil
+}
+
+// expire drops a message whose validity elapsed before it could be sent and
+// refunds its reservation. It reports false when the message is no longer
+// queued, leaving it to the usual duplicate handling.
+func (c *consumer) expire(ctx context.Context, req models.QueuedSMS) (bool, error) {
+	err := db.TransitionMessage(ctx, req.MessageID, models.StatusExpired, "validity elapsed at "+req.ExpiresAt.Format(time.RFC3339))
+	if errors.Is(err, db.ErrInvalidTransition) || err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	metrics.MessagesExpired.WithLabelValues(string(req.Priority)).Inc()
+	logger.WarnCtx(ctx, "Message expired before dispatch",
+		zap.Time("expires_at", *req.ExpiresAt),
+		zap.Duration("late_by", time.Since(*req.ExpiresAt)))
+	return true, c.settle(ctx, req, models.StatusExpired)
+}
+
+// deferUntil holds a marketing message that reached the worker during quiet
+// hours, e.g. from a backlog, until they end. It reports false when the
+// message is no longer queued, leaving it to the usual duplicate handling.
+func (c *consumer) deferUntil(ctx context.Context, req models.QueuedSMS, until time.Time) (bool, error) {
+	parked, err := c.parked(ctx, req)
+	if err != nil {
+		return false, err
+	}
+	err = db.DeferMessage(ctx, parked, "quiet hours until "+until.Format(time.RFC3339), time.Until(until))
+	if errors.Is(err, db.ErrInvalidTransition) || err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	metrics.ParkedMessages.WithLabelValues(db.CauseQuietHours).Inc()
+	logger.InfoCtx(ctx, "Message deferred until quiet hours end", zap.Time("until", until))
+	return true, nil
+}
+
+func (c *consumer) handleVIP(ctx context.Context, req models.QueuedSMS) error {
+	logger.InfoCtx(ctx, "Processing VIP SMS",
 		zap.String("phone_number", req.PhoneNumber))
 
-	sent := sendSMS(req.PhoneNumber, req.Message)
+	providerID, status, err := c.claim(ctx, req)
+	if err != nil || status != models.StatusSending {
+		return err
+	}
+
+	sent, err := c.dispatch(ctx, req.SMSRequest, providerID)
+	if errors.Is(err, breaker.ErrOpen) {
+		return c.requeue(ctx, req, err)
+	}
+	if err != nil {
+		return err
+	}
 	if !sent {
-		db.UpdateMessageStatus(req.MessageID, "failed")
-		logger.Warn("VIP SMS failed", zap.String("message_id", req.MessageID))
-		metrics.KafkaErrors.Inc()
-		return
+		setStatus(ctx, req.MessageID, models.StatusFailed, "provider rejected message")
+		logger.WarnCtx(ctx, "VIP SMS failed")
+		return nil
 	}
 
-	if err := db.DeductBalance(req.UserID, smsCost); err != nil {
-		logger.Error("Failed to deduct balance", zap.Error(err))
-		db.UpdateMessageStatus(req.MessageID, "error")
-		return
+	completed, unpaid, err := db.CompleteMessage(ctx, req.MessageID, req.UserID, smsCost, true)
+	if err != nil {
+		return err
+	}
+	if !completed {
+		logger.WarnCtx(ctx, "VIP SMS already completed")
+		return nil
+	}
+	if unpaid {
+		metrics.UnpaidCharges.Inc()
+		logger.ErrorCtx(ctx, "Insufficient balance to charge sent VIP SMS, charge recorded as unpaid",
+			zap.Int64("amount", smsCost))
+		return nil
 	}
 
-	db.UpdateMessageStatus(req.MessageID, "sent")
-	logger.Info("VIP SMS sent successfully", zap.String("message_id", req.MessageID))
-	metrics.TotalSMSRequests.Inc()
+	logger.InfoCtx(ctx, "VIP SMS sent successfully")
+	return nil
 }
 
-func handleNormal(req models.SMSRequest) {
-	logger.Info("Processing Normal SMS",
-		zap.String("message_id", req.MessageID),
-		zap.String("user_id", req.UserID),
+func (c *consumer) handleNormal(ctx context.Context, req models.QueuedSMS) error {
+	logger.InfoCtx(ctx, "Processing Normal SMS",
 		zap.String("phone_number", req.PhoneNumber))
 
-	// TODO: Reservation handling (MarkUsed or Rollback)
-	sent := sendSMS(req.PhoneNumber, req.Message)
+	providerID, status, err := c.claim(ctx, req)
+	if err != nil {
+		return err
+	}
+	if status != models.StatusSending {
+		// Redelivered after the message reached a final status: settle again in
+		// case the previous attempt crashed between the two steps.
+		return c.settle(ctx, req, status)
+	}
 
-	if sent {
-		db.UpdateMessageStatus(req.MessageID, "sent")
-		logger.Info("Normal SMS sent successfully", zap.String("message_id", req.MessageID))
-		metrics.TotalSMSRequests.Inc()
-	} else {
-		db.UpdateMessageStatus(req.MessageID, "failed")
-		logger.Warn("Normal SMS failed", zap.String("message_id", req.MessageID))
-		metrics.KafkaErrors.Inc()
+	sent, err := c.dispatch(ctx, req.SMSRequest, providerID)
+	if errors.Is(err, breaker.ErrOpen) {
+		return c.requeue(ctx, req, err)
+	}
+	if err != nil {
+		return err
+	}
+	if !sent {
+		// Refund only once the failure is recorded: a message left in sending
+		// is taken over by a later delivery, which settles it then.
+		if err := db.TransitionMessage(ctx, req.MessageID, models.StatusFailed, "provider rejected message"); err != nil {
+			return err
+		}
+		logger.WarnCtx(ctx, "Normal SMS failed")
+		return c.settle(ctx, req, models.StatusFailed)
+	}
+
+	if _, _, err := db.CompleteMessage(ctx, req.MessageID, req.UserID, smsCost, false); err != nil {
+		return err
+	}
+	logger.InfoCtx(ctx, "Normal SMS sent successfully")
+	return c.settle(ctx, req, models.StatusSent)
+}
+
+// settle closes the message's reservation: used once the SMS was sent,
+// refunded when it failed for good. Both operations are idempotent.
+func (c *consumer) settle(ctx context.Context, req models.QueuedSMS, status models.MessageStatus) error {
+	if req.ReservationID == "" {
+		return nil
+	}
+
+	switch status {
+	case models.StatusSent, models.StatusDelivered, models.StatusUndelivered:
+		settled, err := c.reservations.MarkUsed(ctx, req.ReservationID)
+		if err != nil {
+			return err
+		}
+		if settled {
+			logger.InfoCtx(ctx, "Reservation marked used", zap.String("reservation_id", req.ReservationID))
+		}
+	case models.StatusFailed, models.StatusRejected, models.StatusExpired:
+		refunded, err := c.reservations.Refund(ctx, req.ReservationID, "message "+string(status))
+		if err != nil {
+			return err
+		}
+		if refunded {
+			logger.InfoCtx(ctx, "Reservation refunded", zap.String("reservation_id", req.ReservationID))
+		}
+	}
+	return nil
+}
+
+func setStatus(ctx context.Context, messageID string, status models.MessageStatus, reason string) {
+	if err := db.TransitionMessage(ctx, messageID, status, reason); err != nil {
+		logger.ErrorCtx(ctx, "Failed to update message status",
+			zap.String("status", string(status)),
+			zap.Error(err))
+	}
+}
+
+// validityPeriod formats the time left until expiresAt as a relative SMPP
+// validity_period (YYMMDDhhmmss000R), or "" for the provider's default.
+func validityPeriod(expiresAt *time.Time, now time.Time) string {
+	if expiresAt == nil {
+		return ""
 	}
+	left := int(max(expiresAt.Sub(now), time.Second) / time.Second)
+	days := left / 86400
+	return fmt.Sprintf("%02d%02d%02d%02d%02d%02d000R",
+		min(days/365, 99), days%365/30, days%365%30, left%86400/3600, left%3600/60, left%60)
 }
 
-func sendSMS(phone, text string) bool {
+// sendSMS hands the message to the provider and returns the provider's id.
+// sender is the SMPP source address, empty for the provider's default.
+// validity is passed on as the SMPP validity_period, so the provider drops
+// the message too if it cannot deliver it in time.
+func sendSMS(phone, sender, text, validity string) (string, bool) {
 	time.Sleep(10 * time.Millisecond)
-	return true
+	return uuid.New().String(), true
 }
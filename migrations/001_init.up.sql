@@ -25,7 +25,7 @@ CREATE TABLE IF NOT EXISTS messages (
                                         created_at TIMESTAMP DEFAULT NOW()
 );
 
-CREATE TABLE users (
+CREATE TABLE IF NOT EXISTS users (
                        id UUID PRIMARY KEY,
                        balance BIGINT NOT NULL DEFAULT 0,
                        is_vip BOOLEAN NOT NULL DEFAULT FALSE,
@@ -35,7 +35,8 @@ CREATE TABLE users (
 INSERT INTO users (id, balance, is_vip)
 VALUES
     ('11111111-1111-1111-1111-111111111111', 10000000, TRUE),
-    ('22222222-2222-2222-2222-222222222222', 10000000, FALSE);
+    ('22222222-2222-2222-2222-222222222222', 10000000, FALSE)
+ON CONFLICT (id) DO NOTHING;
 
 CREATE INDEX IF NOT EXISTS idx_reservations_user ON reservations(user_id);
-CREATE INDEX IF NOT EXISTS idx_messages_user ON messages(user_id);
\ No newline at end of file
+CREATE INDEX IF NOT EXISTS idx_messages_user ON messages(user_id);
@@ -0,0 +1,7 @@
+UPDATE messages SET status = 'failed' WHERE status = 'sending';
+
+ALTER TABLE messages DROP COLUMN IF EXISTS provider_message_id;
+
+ALTER TABLE messages DROP CONSTRAINT IF EXISTS messages_status_check;
+ALTER TABLE messages ADD CONSTRAINT messages_status_check
+    CHECK (status IN ('queued', 'sent', 'failed', 'rejected'));
@@ -0,0 +1,7 @@
+-- Workers claim a message (queued -> sending) before handing it to the provider,
+-- and remember the provider's message id so a redelivered message is not sent twice.
+ALTER TABLE messages DROP CONSTRAINT IF EXISTS messages_status_check;
+ALTER TABLE messages ADD CONSTRAINT messages_status_check
+    CHECK (status IN ('queued', 'sending', 'sent', 'failed', 'rejected'));
+
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS provider_message_id TEXT;
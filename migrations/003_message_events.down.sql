@@ -0,0 +1,9 @@
+DROP TABLE IF EXISTS message_events;
+
+ALTER TABLE messages DROP COLUMN IF EXISTS updated_at;
+
+UPDATE messages SET status = 'sent' WHERE status IN ('delivered', 'undelivered');
+
+ALTER TABLE messages DROP CONSTRAINT IF EXISTS messages_status_check;
+ALTER TABLE messages ADD CONSTRAINT messages_status_check
+    CHECK (status IN ('queued', 'sending', 'sent', 'failed', 'rejected'));
@@ -0,0 +1,17 @@
+-- Status values are defined by the state machine in internal/models/status.go.
+ALTER TABLE messages DROP CONSTRAINT IF EXISTS messages_status_check;
+ALTER TABLE messages ADD CONSTRAINT messages_status_check
+    CHECK (status IN ('queued', 'sending', 'sent', 'delivered', 'undelivered', 'failed', 'rejected'));
+
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS updated_at TIMESTAMP DEFAULT NOW();
+
+CREATE TABLE IF NOT EXISTS message_events (
+                                              id BIGSERIAL PRIMARY KEY,
+                                              message_id UUID NOT NULL,
+                                              from_status TEXT,
+                                              to_status TEXT NOT NULL,
+                                              reason TEXT NOT NULL DEFAULT '',
+                                              created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_message_events_message ON message_events(message_id, id);
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_reservations_message;
+
+ALTER TABLE reservations DROP CONSTRAINT IF EXISTS reservations_state_check;
+ALTER TABLE reservations DROP COLUMN IF EXISTS settled_at;
+ALTER TABLE reservations DROP COLUMN IF EXISTS state;
+ALTER TABLE reservations DROP COLUMN IF EXISTS message_id;
@@ -0,0 +1,13 @@
+-- A reservation is settled exactly once: marked used when the SMS is sent,
+-- or refunded when sending fails for good.
+ALTER TABLE reservations ADD COLUMN IF NOT EXISTS message_id UUID;
+ALTER TABLE reservations ADD COLUMN IF NOT EXISTS state TEXT NOT NULL DEFAULT 'active';
+ALTER TABLE reservations ADD COLUMN IF NOT EXISTS settled_at TIMESTAMP;
+
+UPDATE reservations SET state = 'used' WHERE used AND state = 'active';
+
+ALTER TABLE reservations DROP CONSTRAINT IF EXISTS reservations_state_check;
+ALTER TABLE reservations ADD CONSTRAINT reservations_state_check
+    CHECK (state IN ('active', 'used', 'refunded'));
+
+CREATE INDEX IF NOT EXISTS idx_reservations_message ON reservations(message_id);
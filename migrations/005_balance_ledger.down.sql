@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_reservations_active_expiry;
+DROP TABLE IF EXISTS balance_ledger;
@@ -0,0 +1,17 @@
+-- Every balance credit made by the system is written to the ledger. The unique
+-- index makes a reservation refundable at most once even if two jobs race.
+CREATE TABLE IF NOT EXISTS balance_ledger (
+                                              id BIGSERIAL PRIMARY KEY,
+                                              user_id UUID NOT NULL,
+                                              amount BIGINT NOT NULL,
+                                              kind TEXT NOT NULL,
+                                              reservation_id UUID,
+                                              message_id UUID,
+                                              reason TEXT NOT NULL DEFAULT '',
+                                              created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_balance_ledger_reservation ON balance_ledger(reservation_id, kind);
+CREATE INDEX IF NOT EXISTS idx_balance_ledger_user ON balance_ledger(user_id, created_at);
+
+CREATE INDEX IF NOT EXISTS idx_reservations_active_expiry ON reservations(expires_at) WHERE state = 'active';
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS balance_alert_thresholds;
+DROP TABLE IF EXISTS balance_alert_settings;
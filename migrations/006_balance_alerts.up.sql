@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS balance_alert_settings (
+                                                      user_id UUID PRIMARY KEY,
+                                                      hysteresis BIGINT NOT NULL DEFAULT 0 CHECK (hysteresis >= 0),
+                                                      notify_phone TEXT,
+                                                      webhook_url TEXT,
+                                                      updated_at TIMESTAMP DEFAULT NOW()
+);
+
+-- triggered is set when the balance drops to or below the threshold and
+-- cleared once it climbs back above threshold + hysteresis.
+CREATE TABLE IF NOT EXISTS balance_alert_thresholds (
+                                                        user_id UUID NOT NULL,
+                                                        threshold BIGINT NOT NULL,
+                                                        triggered BOOLEAN NOT NULL DEFAULT FALSE,
+                                                        triggered_at TIMESTAMP,
+                                                        PRIMARY KEY (user_id, threshold)
+);
@@ -0,0 +1,5 @@
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_balance_floor_check;
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_credit_limit_check;
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_account_type_check;
+ALTER TABLE users DROP COLUMN IF EXISTS credit_limit;
+ALTER TABLE users DROP COLUMN IF EXISTS account_type;
@@ -0,0 +1,20 @@
+-- Postpaid accounts may run their balance down to -credit_limit and are
+-- invoiced for the outstanding amount. Prepaid accounts keep a zero limit.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS account_type TEXT NOT NULL DEFAULT 'prepaid';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS credit_limit BIGINT NOT NULL DEFAULT 0;
+
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_account_type_check;
+ALTER TABLE users ADD CONSTRAINT users_account_type_check
+    CHECK (account_type IN ('prepaid', 'postpaid'));
+
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_credit_limit_check;
+ALTER TABLE users ADD CONSTRAINT users_credit_limit_check
+    CHECK (credit_limit >= 0 AND (account_type = 'postpaid' OR credit_limit = 0));
+
+-- NOT VALID: rows that already drifted below zero are left for the wallet
+-- reconciler to flag; every new debit is checked.
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_balance_floor_check;
+ALTER TABLE users ADD CONSTRAINT users_balance_floor_check
+    CHECK (balance >= -credit_limit) NOT VALID;
+
+ALTER TABLE wallets DROP CONSTRAINT IF EXISTS wallets_balance_check;
@@ -0,0 +1,16 @@
+-- Messages that could not be handed to Kafka or to an open provider. They stay
+-- 'queued' and are republished to topic once next_attempt_at has passed.
+CREATE TABLE IF NOT EXISTS parked_messages (
+                                               message_id UUID PRIMARY KEY,
+                                               topic TEXT NOT NULL,
+                                               msg_key TEXT NOT NULL,
+                                               payload TEXT NOT NULL,
+                                               headers JSONB NOT NULL DEFAULT '{}',
+                                               cause TEXT NOT NULL,
+                                               attempts INT NOT NULL DEFAULT 0,
+                                               last_error TEXT NOT NULL DEFAULT '',
+                                               next_attempt_at TIMESTAMP NOT NULL DEFAULT NOW(),
+                                               created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_parked_messages_next ON parked_messages(next_attempt_at);
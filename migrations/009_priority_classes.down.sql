@@ -0,0 +1,4 @@
+ALTER TABLE messages DROP CONSTRAINT IF EXISTS messages_priority_check;
+ALTER TABLE messages DROP COLUMN IF EXISTS priority;
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_allowed_priorities_check;
+ALTER TABLE users DROP COLUMN IF EXISTS allowed_priorities;
@@ -0,0 +1,15 @@
+-- Classes a user may send with; VIP users start with every class.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS allowed_priorities TEXT[] NOT NULL DEFAULT ARRAY['transactional', 'marketing'];
+
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_allowed_priorities_check;
+ALTER TABLE users ADD CONSTRAINT users_allowed_priorities_check
+    CHECK (allowed_priorities <@ ARRAY['otp', 'transactional', 'marketing']);
+
+UPDATE users SET allowed_priorities = ARRAY['otp', 'transactional', 'marketing']
+WHERE is_vip AND NOT ('otp' = ANY(allowed_priorities));
+
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS priority TEXT NOT NULL DEFAULT 'transactional';
+
+ALTER TABLE messages DROP CONSTRAINT IF EXISTS messages_priority_check;
+ALTER TABLE messages ADD CONSTRAINT messages_priority_check
+    CHECK (priority IN ('otp', 'transactional', 'marketing'));
@@ -0,0 +1,2 @@
+ALTER TABLE messages DROP COLUMN IF EXISTS otp_verified_at;
+ALTER TABLE messages DROP COLUMN IF EXISTS otp_verified;
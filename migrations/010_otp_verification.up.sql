@@ -0,0 +1,3 @@
+-- Set for messages sent through /otp/send: false until the code is verified.
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS otp_verified BOOLEAN;
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS otp_verified_at TIMESTAMP;
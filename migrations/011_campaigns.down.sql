@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_messages_campaign;
+ALTER TABLE messages DROP COLUMN IF EXISTS campaign_id;
+DROP TABLE IF EXISTS campaign_recipients;
+DROP TABLE IF EXISTS campaigns;
@@ -0,0 +1,34 @@
+CREATE TABLE IF NOT EXISTS campaigns (
+                                         id UUID PRIMARY KEY,
+                                         user_id UUID NOT NULL,
+                                         name TEXT NOT NULL,
+                                         body TEXT NOT NULL,
+                                         status TEXT NOT NULL DEFAULT 'draft'
+                                             CHECK (status IN ('draft', 'running', 'paused', 'completed', 'cancelled')),
+                                         rate_per_second INT NOT NULL CHECK (rate_per_second > 0),
+                                         last_error TEXT NOT NULL DEFAULT '',
+                                         next_dispatch_at TIMESTAMP NOT NULL DEFAULT NOW(),
+                                         created_at TIMESTAMP DEFAULT NOW(),
+                                         started_at TIMESTAMP,
+                                         finished_at TIMESTAMP,
+                                         updated_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_campaigns_user ON campaigns(user_id);
+CREATE INDEX IF NOT EXISTS idx_campaigns_due ON campaigns(next_dispatch_at) WHERE status = 'running';
+
+-- message_id is assigned on upload so a recipient is never sent under two ids.
+CREATE TABLE IF NOT EXISTS campaign_recipients (
+                                                   id BIGSERIAL PRIMARY KEY,
+                                                   campaign_id UUID NOT NULL REFERENCES campaigns(id) ON DELETE CASCADE,
+                                                   phone_number TEXT NOT NULL,
+                                                   variables JSONB NOT NULL DEFAULT '{}',
+                                                   message_id UUID NOT NULL UNIQUE,
+                                                   dispatched_at TIMESTAMP,
+                                                   UNIQUE (campaign_id, phone_number)
+);
+
+CREATE INDEX IF NOT EXISTS idx_campaign_recipients_pending ON campaign_recipients(campaign_id, id) WHERE dispatched_at IS NULL;
+
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS campaign_id UUID;
+CREATE INDEX IF NOT EXISTS idx_messages_campaign ON messages(campaign_id) WHERE campaign_id IS NOT NULL;
@@ -0,0 +1,8 @@
+-- Their parked records are still republished; they are sent as queued messages.
+UPDATE messages SET status = 'queued' WHERE status = 'deferred';
+
+ALTER TABLE messages DROP COLUMN IF EXISTS deferred_until;
+
+ALTER TABLE messages DROP CONSTRAINT IF EXISTS messages_status_check;
+ALTER TABLE messages ADD CONSTRAINT messages_status_check
+    CHECK (status IN ('queued', 'sending', 'sent', 'delivered', 'undelivered', 'failed', 'rejected'));
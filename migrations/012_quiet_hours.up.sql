@@ -0,0 +1,7 @@
+-- Marketing messages accepted or consumed during quiet hours wait in
+-- parked_messages as 'deferred' until deferred_until.
+ALTER TABLE messages DROP CONSTRAINT IF EXISTS messages_status_check;
+ALTER TABLE messages ADD CONSTRAINT messages_status_check
+    CHECK (status IN ('queued', 'sending', 'sent', 'delivered', 'undelivered', 'failed', 'rejected', 'deferred'));
+
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS deferred_until TIMESTAMP;
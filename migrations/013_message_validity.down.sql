@@ -0,0 +1,7 @@
+UPDATE messages SET status = 'failed' WHERE status = 'expired';
+
+ALTER TABLE messages DROP COLUMN IF EXISTS expires_at;
+
+ALTER TABLE messages DROP CONSTRAINT IF EXISTS messages_status_check;
+ALTER TABLE messages ADD CONSTRAINT messages_status_check
+    CHECK (status IN ('queued', 'sending', 'sent', 'delivered', 'undelivered', 'failed', 'rejected', 'deferred'));
@@ -0,0 +1,7 @@
+-- Messages whose validity elapsed before a worker sent them are 'expired'
+-- and their reservation is refunded.
+ALTER TABLE messages DROP CONSTRAINT IF EXISTS messages_status_check;
+ALTER TABLE messages ADD CONSTRAINT messages_status_check
+    CHECK (status IN ('queued', 'sending', 'sent', 'delivered', 'undelivered', 'failed', 'rejected', 'deferred', 'expired'));
+
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS expires_at TIMESTAMP;
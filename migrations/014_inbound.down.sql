@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS inbound_messages;
+DROP TABLE IF EXISTS inbound_routes;
@@ -0,0 +1,38 @@
+-- Numbers and keywords that route mobile-originated (MO) messages to a user.
+-- An empty keyword matches any text sent to the number.
+CREATE TABLE IF NOT EXISTS inbound_routes (
+                                              id BIGSERIAL PRIMARY KEY,
+                                              number TEXT NOT NULL,
+                                              keyword TEXT NOT NULL DEFAULT '',
+                                              user_id UUID NOT NULL,
+                                              webhook_url TEXT NOT NULL DEFAULT '',
+                                              created_at TIMESTAMP DEFAULT NOW(),
+                                              UNIQUE (number, keyword)
+);
+
+CREATE INDEX IF NOT EXISTS idx_inbound_routes_user ON inbound_routes(user_id);
+
+-- seq orders a user's messages for GET /inbound. user_id is NULL for
+-- messages no route matched.
+CREATE TABLE IF NOT EXISTS inbound_messages (
+                                                id UUID PRIMARY KEY,
+                                                seq BIGSERIAL UNIQUE,
+                                                provider TEXT NOT NULL,
+                                                provider_message_id TEXT NOT NULL,
+                                                from_number TEXT NOT NULL,
+                                                to_number TEXT NOT NULL,
+                                                text TEXT NOT NULL,
+                                                keyword TEXT NOT NULL DEFAULT '',
+                                                user_id UUID,
+                                                route_id BIGINT REFERENCES inbound_routes(id) ON DELETE SET NULL,
+                                                webhook_status TEXT NOT NULL DEFAULT 'none'
+                                                    CHECK (webhook_status IN ('none', 'pending', 'delivered', 'failed')),
+                                                webhook_attempts INT NOT NULL DEFAULT 0,
+                                                webhook_last_error TEXT NOT NULL DEFAULT '',
+                                                next_webhook_at TIMESTAMP,
+                                                received_at TIMESTAMP NOT NULL DEFAULT NOW(),
+                                                UNIQUE (provider, provider_message_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_inbound_messages_user ON inbound_messages(user_id, seq);
+CREATE INDEX IF NOT EXISTS idx_inbound_messages_webhook ON inbound_messages(next_webhook_at) WHERE webhook_status = 'pending';
@@ -0,0 +1,6 @@
+ALTER TABLE inbound_messages DROP COLUMN IF EXISTS reply_message_id;
+ALTER TABLE inbound_messages DROP COLUMN IF EXISTS auto_reply_rule_id;
+DROP TABLE IF EXISTS contact_tags;
+DROP TABLE IF EXISTS opt_outs;
+DROP TABLE IF EXISTS auto_reply_cooldowns;
+DROP TABLE IF EXISTS auto_reply_rules;
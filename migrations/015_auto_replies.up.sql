@@ -0,0 +1,47 @@
+-- Rules answering inbound messages to a routed number. keyword is empty
+-- only for match_type 'any'.
+CREATE TABLE IF NOT EXISTS auto_reply_rules (
+                                                id BIGSERIAL PRIMARY KEY,
+                                                user_id UUID NOT NULL,
+                                                number TEXT NOT NULL,
+                                                match_type TEXT NOT NULL
+                                                    CHECK (match_type IN ('exact', 'prefix', 'contains', 'any')),
+                                                keyword TEXT NOT NULL DEFAULT '',
+                                                reply TEXT NOT NULL DEFAULT '',
+                                                action TEXT NOT NULL DEFAULT ''
+                                                    CHECK (action IN ('', 'opt_out', 'opt_in', 'tag')),
+                                                tag TEXT NOT NULL DEFAULT '',
+                                                cooldown_seconds INT NOT NULL DEFAULT 0 CHECK (cooldown_seconds >= 0),
+                                                created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_auto_reply_rules_number ON auto_reply_rules(user_id, number);
+
+-- Last reply per rule and sender, for the rule's cooldown.
+CREATE TABLE IF NOT EXISTS auto_reply_cooldowns (
+                                                    rule_id BIGINT NOT NULL REFERENCES auto_reply_rules(id) ON DELETE CASCADE,
+                                                    sender TEXT NOT NULL,
+                                                    replied_at TIMESTAMP NOT NULL DEFAULT NOW(),
+                                                    PRIMARY KEY (rule_id, sender)
+);
+
+-- Numbers that asked a user to stop marketing messages.
+CREATE TABLE IF NOT EXISTS opt_outs (
+                                        user_id UUID NOT NULL,
+                                        phone_number TEXT NOT NULL,
+                                        rule_id BIGINT REFERENCES auto_reply_rules(id) ON DELETE SET NULL,
+                                        created_at TIMESTAMP DEFAULT NOW(),
+                                        PRIMARY KEY (user_id, phone_number)
+);
+
+CREATE TABLE IF NOT EXISTS contact_tags (
+                                            user_id UUID NOT NULL,
+                                            phone_number TEXT NOT NULL,
+                                            tag TEXT NOT NULL,
+                                            created_at TIMESTAMP DEFAULT NOW(),
+                                            PRIMARY KEY (user_id, phone_number, tag)
+);
+
+ALTER TABLE inbound_messages ADD COLUMN IF NOT EXISTS auto_reply_rule_id BIGINT
+    REFERENCES auto_reply_rules(id) ON DELETE SET NULL;
+ALTER TABLE inbound_messages ADD COLUMN IF NOT EXISTS reply_message_id UUID;
@@ -0,0 +1,3 @@
+ALTER TABLE campaigns DROP COLUMN IF EXISTS shorten_links;
+DROP TABLE IF EXISTS link_clicks;
+DROP TABLE IF EXISTS short_links;
@@ -0,0 +1,24 @@
+-- Short links replacing URLs in message bodies; one token per URL and
+-- message, so clicks are counted per message.
+CREATE TABLE IF NOT EXISTS short_links (
+                                           token TEXT PRIMARY KEY,
+                                           message_id UUID NOT NULL REFERENCES messages(message_id) ON DELETE CASCADE,
+                                           campaign_id UUID,
+                                           url TEXT NOT NULL,
+                                           created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_short_links_message ON short_links(message_id);
+CREATE INDEX IF NOT EXISTS idx_short_links_campaign ON short_links(campaign_id) WHERE campaign_id IS NOT NULL;
+
+CREATE TABLE IF NOT EXISTS link_clicks (
+                                           id BIGSERIAL PRIMARY KEY,
+                                           token TEXT NOT NULL REFERENCES short_links(token) ON DELETE CASCADE,
+                                           ip TEXT NOT NULL DEFAULT '',
+                                           user_agent TEXT NOT NULL DEFAULT '',
+                                           clicked_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_link_clicks_token ON link_clicks(token);
+
+ALTER TABLE campaigns ADD COLUMN IF NOT EXISTS shorten_links BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,11 @@
+-- Send caps per calendar period. priority '' covers all classes; usage is
+-- counted in Redis.
+CREATE TABLE IF NOT EXISTS quotas (
+                                      user_id UUID NOT NULL,
+                                      priority TEXT NOT NULL DEFAULT ''
+                                          CHECK (priority IN ('', 'otp', 'transactional', 'marketing')),
+                                      period TEXT NOT NULL CHECK (period IN ('day', 'month')),
+                                      max_messages BIGINT NOT NULL CHECK (max_messages >= 0),
+                                      updated_at TIMESTAMP DEFAULT NOW(),
+                                      PRIMARY KEY (user_id, priority, period)
+);
@@ -0,0 +1,3 @@
+-- When a worker claimed a message. A claim that never reached the provider
+-- is taken over by the next delivery once it is older than the claim lease.
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS claimed_at TIMESTAMP;
@@ -0,0 +1,2 @@
+-- The originator the message is sent with; NULL uses the provider's default.
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS sender_id TEXT;
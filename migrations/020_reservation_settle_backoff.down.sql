@@ -0,0 +1,2 @@
+ALTER TABLE reservations DROP COLUMN IF EXISTS next_attempt_at;
+ALTER TABLE reservations DROP COLUMN IF EXISTS settle_attempts;
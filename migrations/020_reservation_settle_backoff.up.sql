@@ -0,0 +1,4 @@
+-- A reservation the refund job failed to settle is retried with backoff, so
+-- it is not picked first again by every batch.
+ALTER TABLE reservations ADD COLUMN IF NOT EXISTS settle_attempts INT NOT NULL DEFAULT 0;
+ALTER TABLE reservations ADD COLUMN IF NOT EXISTS next_attempt_at TIMESTAMP;